@@ -0,0 +1,57 @@
+package camera
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Camera looks at Position, which is shown in the center of a Width x Height
+// screen. Offset is added on top of Position for transient effects such as
+// screen shake without disturbing the tracked position.
+type Camera struct {
+	Position mgl32.Vec2
+	Offset   mgl32.Vec2
+	Zoom     float32
+	Rotation float32
+
+	Width  float32
+	Height float32
+}
+
+func (c *Camera) View() mgl32.Mat4 {
+	center := c.Position.Add(c.Offset)
+
+	view := mgl32.Translate3D(c.Width/2, c.Height/2, 0)
+	view = view.Mul4(mgl32.Scale3D(c.Zoom, c.Zoom, 1))
+	view = view.Mul4(mgl32.HomogRotate3DZ(-c.Rotation))
+	view = view.Mul4(mgl32.Translate3D(-center.X(), -center.Y(), 0))
+	return view
+}
+
+func (c *Camera) Reset() {
+	c.Position = mgl32.Vec2{c.Width / 2, c.Height / 2}
+	c.Offset = mgl32.Vec2{}
+	c.Zoom = 1
+	c.Rotation = 0
+}
+
+// ScreenToWorld converts a point in screen coordinates into world
+// coordinates under the current view.
+func (c *Camera) ScreenToWorld(p mgl32.Vec2) mgl32.Vec2 {
+	world := c.View().Inv().Mul4x1(mgl32.Vec4{p.X(), p.Y(), 0, 1})
+	return mgl32.Vec2{world.X(), world.Y()}
+}
+
+func New(width, height float32) *Camera {
+	c := &Camera{
+		Width:  width,
+		Height: height,
+	}
+	c.Reset()
+	return c
+}
+
+// NewScreen returns a camera for screen-space rendering such as the HUD. It
+// is a regular camera left at its identity view.
+func NewScreen(width, height float32) *Camera {
+	return New(width, height)
+}
@@ -0,0 +1,155 @@
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+
+	"github.com/le-michael/breakout/framebuffer"
+)
+
+// Read reads a region of the current read framebuffer into an image. GL
+// returns the bottom row first, the rows are flipped so the image is upright.
+func Read(x, y, width, height int32) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gl.ReadPixels(x, y, width, height, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(img.Pix))
+
+	flip(img)
+	return img
+}
+
+// ReadFramebuffer reads the color attachment of an offscreen render target.
+func ReadFramebuffer(fb *framebuffer.Framebuffer) *image.RGBA {
+	var prev int32
+	gl.GetIntegerv(gl.READ_FRAMEBUFFER_BINDING, &prev)
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, fb.ReadID())
+	defer gl.BindFramebuffer(gl.READ_FRAMEBUFFER, uint32(prev))
+
+	return Read(0, 0, fb.Width, fb.Height)
+}
+
+func flip(img *image.RGBA) {
+	height := img.Rect.Dy()
+	row := make([]uint8, img.Stride)
+	for y := 0; y < height/2; y++ {
+		top := img.Pix[y*img.Stride : (y+1)*img.Stride]
+		bottom := img.Pix[(height-1-y)*img.Stride : (height-y)*img.Stride]
+		copy(row, top)
+		copy(top, bottom)
+		copy(bottom, row)
+	}
+}
+
+// Every pixel read back is opaque, the framebuffer alpha is meaningless.
+func opaque(img *image.RGBA) {
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+}
+
+func savePNG(file string, img *image.RGBA) error {
+	opaque(img)
+
+	f, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("unable to create %v: %v", file, err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to encode %v: %v", file, err)
+	}
+	return f.Close()
+}
+
+func timestamp() string {
+	return time.Now().Format("20060102-150405.000")
+}
+
+// Screenshot writes img into dir under a timestamped name and returns the
+// file it wrote.
+func Screenshot(dir string, img *image.RGBA) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("unable to create %v: %v", dir, err)
+	}
+	file := filepath.Join(dir, fmt.Sprintf("breakout-%v.png", timestamp()))
+	return file, savePNG(file, img)
+}
+
+// Recorder captures a sequence of consecutive frames, for turning into a GIF
+// or video. Frames are encoded on background goroutines so capturing does
+// not stall the game loop.
+type Recorder struct {
+	Dir string
+
+	prefix    string
+	frame     int
+	remaining int
+
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	errs []string
+}
+
+// Start begins recording the next frames frames. Any recording in progress
+// is stopped.
+func (r *Recorder) Start(frames int) error {
+	r.prefix = filepath.Join(r.Dir, "breakout-"+timestamp())
+	if err := os.MkdirAll(r.prefix, 0755); err != nil {
+		return fmt.Errorf("unable to create %v: %v", r.prefix, err)
+	}
+	r.frame = 0
+	r.remaining = frames
+	return nil
+}
+
+func (r *Recorder) Recording() bool {
+	return r.remaining > 0
+}
+
+// Capture records the current frame if a recording is in progress. It must
+// be called after rendering and before swapping buffers.
+func (r *Recorder) Capture(x, y, width, height int32) {
+	if r.remaining <= 0 {
+		return
+	}
+	img := Read(x, y, width, height)
+	file := filepath.Join(r.prefix, fmt.Sprintf("%05d.png", r.frame))
+	r.frame++
+	r.remaining--
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		if err := savePNG(file, img); err != nil {
+			r.mu.Lock()
+			r.errs = append(r.errs, err.Error())
+			r.mu.Unlock()
+		}
+	}()
+}
+
+// Wait blocks until every captured frame is written.
+func (r *Recorder) Wait() error {
+	r.wg.Wait()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.errs) == 0 {
+		return nil
+	}
+	err := errors.New(strings.Join(r.errs, "\n"))
+	r.errs = nil
+	return err
+}
+
+func NewRecorder(dir string) *Recorder {
+	return &Recorder{Dir: dir}
+}
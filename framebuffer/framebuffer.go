@@ -0,0 +1,156 @@
+package framebuffer
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+
+	"github.com/le-michael/breakout/texture"
+)
+
+type Options struct {
+	// Samples above 1 render into multisampled buffers that are resolved
+	// into Texture on Unbind.
+	Samples int
+	Depth   bool
+	Stencil bool
+}
+
+// Framebuffer is an offscreen render target. Its color attachment is
+// available as Texture once unbound.
+//
+// GL textures start at the bottom row, so Texture comes out upside down
+// when drawn by the sprite renderer. Draw it with a negative height, offset
+// by the height, to flip it back.
+type Framebuffer struct {
+	ID      uint32
+	Width   int32
+	Height  int32
+	Samples int32
+
+	Texture *texture.Texture2D
+
+	// Only set when multisampled, ID then renders into colorRBO and is
+	// blitted into resolveID which holds Texture.
+	resolveID uint32
+	colorRBO  uint32
+
+	depthRBO uint32
+
+	prevFBO      int32
+	prevViewport [4]int32
+}
+
+// Bind directs rendering into the framebuffer and sets the viewport to cover
+// it. The previous framebuffer and viewport are restored by Unbind.
+func (f *Framebuffer) Bind() {
+	gl.GetIntegerv(gl.DRAW_FRAMEBUFFER_BINDING, &f.prevFBO)
+	gl.GetIntegerv(gl.VIEWPORT, &f.prevViewport[0])
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, f.ID)
+	gl.Viewport(0, 0, f.Width, f.Height)
+}
+
+func (f *Framebuffer) Unbind() {
+	if f.resolveID != 0 {
+		f.Resolve()
+	}
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(f.prevFBO))
+	gl.Viewport(f.prevViewport[0], f.prevViewport[1], f.prevViewport[2], f.prevViewport[3])
+}
+
+// Resolve copies the multisampled color buffer into Texture.
+func (f *Framebuffer) Resolve() {
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, f.ID)
+	gl.BindFramebuffer(gl.DRAW_FRAMEBUFFER, f.resolveID)
+	gl.BlitFramebuffer(0, 0, f.Width, f.Height, 0, 0, f.Width, f.Height, gl.COLOR_BUFFER_BIT, gl.NEAREST)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, f.ID)
+}
+
+// ReadID is the framebuffer holding the final color image, to read pixels
+// from after Unbind.
+func (f *Framebuffer) ReadID() uint32 {
+	if f.resolveID != 0 {
+		return f.resolveID
+	}
+	return f.ID
+}
+
+func (f *Framebuffer) Delete() {
+	gl.DeleteFramebuffers(1, &f.ID)
+	if f.resolveID != 0 {
+		gl.DeleteFramebuffers(1, &f.resolveID)
+	}
+	if f.colorRBO != 0 {
+		gl.DeleteRenderbuffers(1, &f.colorRBO)
+	}
+	if f.depthRBO != 0 {
+		gl.DeleteRenderbuffers(1, &f.depthRBO)
+	}
+	f.Texture.Delete()
+	*f = Framebuffer{}
+}
+
+func New(width, height int, opts Options) (*Framebuffer, error) {
+	f := &Framebuffer{
+		Width:   int32(width),
+		Height:  int32(height),
+		Samples: int32(opts.Samples),
+	}
+
+	var prevFBO int32
+	gl.GetIntegerv(gl.FRAMEBUFFER_BINDING, &prevFBO)
+	defer gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(prevFBO))
+
+	f.Texture = texture.NewWithOptions(texture.Options{Alpha: true, Wrap: texture.WrapClamp})
+	f.Texture.Allocate(width, height)
+
+	gl.GenFramebuffers(1, &f.ID)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, f.ID)
+
+	if opts.Samples > 1 {
+		gl.GenRenderbuffers(1, &f.colorRBO)
+		gl.BindRenderbuffer(gl.RENDERBUFFER, f.colorRBO)
+		gl.RenderbufferStorageMultisample(gl.RENDERBUFFER, f.Samples, gl.RGBA8, f.Width, f.Height)
+		gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.RENDERBUFFER, f.colorRBO)
+	} else {
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, f.Texture.ID, 0)
+	}
+
+	if opts.Depth || opts.Stencil {
+		format, attachment := uint32(gl.DEPTH24_STENCIL8), uint32(gl.DEPTH_STENCIL_ATTACHMENT)
+		if !opts.Stencil {
+			format, attachment = gl.DEPTH_COMPONENT24, gl.DEPTH_ATTACHMENT
+		} else if !opts.Depth {
+			format, attachment = gl.STENCIL_INDEX8, gl.STENCIL_ATTACHMENT
+		}
+
+		gl.GenRenderbuffers(1, &f.depthRBO)
+		gl.BindRenderbuffer(gl.RENDERBUFFER, f.depthRBO)
+		if opts.Samples > 1 {
+			gl.RenderbufferStorageMultisample(gl.RENDERBUFFER, f.Samples, format, f.Width, f.Height)
+		} else {
+			gl.RenderbufferStorage(gl.RENDERBUFFER, format, f.Width, f.Height)
+		}
+		gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, attachment, gl.RENDERBUFFER, f.depthRBO)
+	}
+	gl.BindRenderbuffer(gl.RENDERBUFFER, 0)
+
+	if status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER); status != gl.FRAMEBUFFER_COMPLETE {
+		f.Delete()
+		return nil, fmt.Errorf("framebuffer incomplete: 0x%x", status)
+	}
+
+	if opts.Samples > 1 {
+		gl.GenFramebuffers(1, &f.resolveID)
+		gl.BindFramebuffer(gl.FRAMEBUFFER, f.resolveID)
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, f.Texture.ID, 0)
+		if status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER); status != gl.FRAMEBUFFER_COMPLETE {
+			f.Delete()
+			return nil, fmt.Errorf("resolve framebuffer incomplete: 0x%x", status)
+		}
+	}
+
+	return f, nil
+}
@@ -0,0 +1,265 @@
+package game
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/le-michael/breakout/object"
+)
+
+// BallConfig tunes the ball's speed and the angles it may travel at.
+type BallConfig struct {
+	Radius float32
+	// Velocity is the launch velocity of a new ball.
+	Velocity mgl32.Vec2
+
+	// The ball speeds up with every brick hit, for every row of bricks it
+	// reaches further into the wall, and over time, up to MaxSpeed. A new
+	// ball starts over at the launch speed.
+	SpeedPerHit    float32
+	SpeedPerRow    float32
+	SpeedPerSecond float32
+	MaxSpeed       float32
+
+	// The ball is kept at least MinAngle degrees away from horizontal, so
+	// it comes back in reasonable time, and MinVerticalAngle degrees away
+	// from vertical, so it cannot bounce straight up and down forever.
+	MinAngle         float32
+	MinVerticalAngle float32
+
+	// After LoopBounces bounces without touching the paddle or breaking a
+	// brick the ball is assumed to be stuck between solid bricks and is
+	// turned by NudgeAngle degrees, alternating sides.
+	LoopBounces int
+	NudgeAngle  float32
+
+	// Collide makes balls bounce off each other instead of passing through.
+	// Levels can turn it on for themselves.
+	Collide bool
+
+	// Breaking a multiball brick splits every ball into MultiBallCount
+	// balls, fanned out by MultiBallAngle degrees.
+	MultiBallCount int
+	MultiBallAngle float32
+}
+
+var DefaultBall = BallConfig{
+	Radius:   12.5,
+	Velocity: mgl32.Vec2{-100, -350},
+
+	SpeedPerHit:    3,
+	SpeedPerRow:    15,
+	SpeedPerSecond: 1,
+	MaxSpeed:       800,
+
+	MinAngle:         20,
+	MinVerticalAngle: 5,
+
+	LoopBounces: 24,
+	NudgeAngle:  15,
+
+	MultiBallCount: 3,
+	MultiBallAngle: 20,
+}
+
+// ballState is what the speed and loop rules track for a ball in play.
+type ballState struct {
+	// owner is the player who touched the ball last and scores for it.
+	// A stuck ball sticks to its owner's paddle.
+	owner *Player
+	// rows is the number of brick rows the ball has reached, counted from
+	// the side of the wall facing its owner.
+	rows    int
+	bounces int
+	nudges  int
+}
+
+func (g *Game) addBall(pos, vel mgl32.Vec2, owner *Player) *object.Ball {
+	ball := object.NewBall(pos, g.BallConfig.Radius, vel, g.ballSprite)
+	ball.Stuck = false
+	ball.OpenTop = g.team(true) != nil
+	g.Balls = append(g.Balls, ball)
+	g.balls[ball] = &ballState{owner: owner}
+	return ball
+}
+
+// MultiBall splits every ball in play into count balls, fanned out by
+// angle degrees. Stuck balls are left alone.
+func (g *Game) MultiBall(count int, angle float32) {
+	for _, ball := range append([]*object.Ball{}, g.Balls...) {
+		if ball.Stuck {
+			continue
+		}
+		for i := 1; i < count; i++ {
+			turn := angle * float32((i+1)/2)
+			if i%2 == 0 {
+				turn = -turn
+			}
+			vel := mgl32.Rotate2D(mgl32.DegToRad(turn)).Mul2x1(ball.Velocity)
+			split := g.addBall(ball.Position, limitAngle(vel, g.BallConfig.MinAngle, g.BallConfig.MinVerticalAngle), nil)
+			*g.balls[split] = *g.balls[ball]
+		}
+	}
+}
+
+// removeLostBalls drops the balls that left the playfield and returns the
+// team whose edge the last of them went past.
+func (g *Game) removeLostBalls() *Team {
+	var lost *Team
+	kept := g.Balls[:0]
+	for _, ball := range g.Balls {
+		switch {
+		case ball.Position.Y() >= float32(g.Height):
+			lost = g.team(false)
+		case ball.OpenTop && ball.Position.Y()+ball.Size.Y() <= 0:
+			lost = g.team(true)
+		default:
+			kept = append(kept, ball)
+			continue
+		}
+		delete(g.balls, ball)
+	}
+	for i := len(kept); i < len(g.Balls); i++ {
+		g.Balls[i] = nil
+	}
+	g.Balls = kept
+	return lost
+}
+
+// updateBall applies the speed and angle rules after the ball moved and
+// collided. before is the velocity it had at the start of the frame.
+func (g *Game) updateBall(ball *object.Ball, dt float32, before mgl32.Vec2) {
+	cfg := g.BallConfig
+	state := g.balls[ball]
+	if ball.Stuck {
+		return
+	}
+
+	speedUp := cfg.SpeedPerSecond * dt
+	if rows := g.rowsReached(ball); rows > state.rows {
+		speedUp += float32(rows-state.rows) * cfg.SpeedPerRow
+		state.rows = rows
+	}
+
+	if (before.X() > 0) != (ball.Velocity.X() > 0) || (before.Y() > 0) != (ball.Velocity.Y() > 0) {
+		state.bounces++
+	}
+	if cfg.LoopBounces > 0 && state.bounces >= cfg.LoopBounces {
+		state.bounces = 0
+		state.nudges++
+		angle := cfg.NudgeAngle
+		if state.nudges%2 == 0 {
+			angle = -angle
+		}
+		ball.Velocity = mgl32.Rotate2D(mgl32.DegToRad(angle)).Mul2x1(ball.Velocity)
+	}
+
+	speed := ball.Velocity.Len() + speedUp
+	if cfg.MaxSpeed > 0 && speed > cfg.MaxSpeed {
+		speed = cfg.MaxSpeed
+	}
+	if speed > 0 && ball.Velocity.Len() > 0 {
+		ball.Velocity = ball.Velocity.Normalize().Mul(speed)
+	}
+	ball.Velocity = limitAngle(ball.Velocity, cfg.MinAngle, cfg.MinVerticalAngle)
+}
+
+// ballHitBrick speeds the ball up. Breaking a brick proves the ball is not
+// stuck in a loop.
+func (g *Game) ballHitBrick(ball *object.Ball, broke bool) {
+	ball.Velocity = ball.Velocity.Normalize().Mul(ball.Velocity.Len() + g.BallConfig.SpeedPerHit)
+	if broke {
+		g.balls[ball].bounces = 0
+	}
+}
+
+// collideBalls bounces balls off each other. Balls weigh the same, so they
+// trade the parts of their velocities along the line between them.
+func (g *Game) collideBalls() {
+	for i, a := range g.Balls {
+		for _, b := range g.Balls[i+1:] {
+			if a.Stuck || b.Stuck {
+				continue
+			}
+			delta := b.Position.Sub(a.Position)
+			dist := delta.Len()
+			overlap := a.Radius + b.Radius - dist
+			if overlap <= 0 || dist == 0 {
+				continue
+			}
+			normal := delta.Mul(1 / dist)
+
+			// Push them apart so they do not collide again next frame.
+			a.Position = a.Position.Sub(normal.Mul(overlap / 2))
+			b.Position = b.Position.Add(normal.Mul(overlap / 2))
+
+			approach := a.Velocity.Sub(b.Velocity).Dot(normal)
+			if approach <= 0 {
+				continue
+			}
+			a.Velocity = a.Velocity.Sub(normal.Mul(approach))
+			b.Velocity = b.Velocity.Add(normal.Mul(approach))
+		}
+	}
+}
+
+// rowsReached returns how many brick rows the ball got past, counted from
+// the side of the wall facing its owner.
+func (g *Game) rowsReached(ball *object.Ball) int {
+	bricks := g.Levels[g.level].Bricks
+	if len(bricks) == 0 {
+		return 0
+	}
+	rowHeight := bricks[0].Size.Y()
+	top, bottom := bricks[0].Position.Y(), float32(0)
+	for _, brick := range bricks {
+		if y := brick.Position.Y(); y < top {
+			top = y
+		}
+		if y := brick.Position.Y() + brick.Size.Y(); y > bottom {
+			bottom = y
+		}
+	}
+	if rowHeight <= 0 {
+		return 0
+	}
+
+	if owner := g.balls[ball].owner; owner != nil && owner.Team.Top {
+		y := ball.Position.Y() + ball.Size.Y()
+		if y <= top {
+			return 0
+		}
+		return int((y - top) / rowHeight)
+	}
+	y := ball.Position.Y()
+	if y >= bottom {
+		return 0
+	}
+	return int((bottom - y) / rowHeight)
+}
+
+// limitAngle turns v so it is at least minAngle degrees away from
+// horizontal and minVerticalAngle degrees away from vertical, keeping its
+// length and general direction.
+func limitAngle(v mgl32.Vec2, minAngle, minVerticalAngle float32) mgl32.Vec2 {
+	speed := v.Len()
+	if speed == 0 {
+		return v
+	}
+
+	angle := math.Atan2(float64(abs(v.Y())), float64(abs(v.X())))
+	angle = math.Max(angle, float64(mgl32.DegToRad(minAngle)))
+	angle = math.Min(angle, math.Pi/2-float64(mgl32.DegToRad(minVerticalAngle)))
+
+	x := speed * float32(math.Cos(angle))
+	y := speed * float32(math.Sin(angle))
+	if v.X() < 0 {
+		x = -x
+	}
+	// Flat balls are sent up.
+	if v.Y() <= 0 {
+		y = -y
+	}
+	return mgl32.Vec2{x, y}
+}
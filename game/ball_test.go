@@ -0,0 +1,266 @@
+package game
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/le-michael/breakout/level"
+	"github.com/le-michael/breakout/object"
+)
+
+// clearBalls takes every ball out of play, for tests that place their own.
+func clearBalls(g *Game) {
+	g.Balls = nil
+	g.balls = make(map[*object.Ball]*ballState)
+}
+
+func TestLimitAngle(t *testing.T) {
+	rad := func(deg float32) float32 { return mgl32.DegToRad(deg) }
+	at := func(speed, deg float32, right, up bool) mgl32.Vec2 {
+		v := mgl32.Vec2{speed * float32(math.Cos(float64(rad(deg)))), speed * float32(math.Sin(float64(rad(deg))))}
+		if !right {
+			v[0] = -v[0]
+		}
+		if up {
+			v[1] = -v[1]
+		}
+		return v
+	}
+
+	tests := []struct {
+		name string
+		in   mgl32.Vec2
+		want mgl32.Vec2
+	}{
+		{"flat right", mgl32.Vec2{400, 0}, at(400, 20, true, true)},
+		{"flat left", mgl32.Vec2{-400, 0}, at(400, 20, false, true)},
+		{"shallow down", at(400, 3, true, false), at(400, 20, true, false)},
+		{"vertical up", mgl32.Vec2{0, -400}, at(400, 85, true, true)},
+		{"vertical down", mgl32.Vec2{0, 400}, at(400, 85, true, false)},
+		{"steep left", at(400, 88, false, true), at(400, 85, false, true)},
+		{"in range", mgl32.Vec2{300, -400}, mgl32.Vec2{300, -400}},
+		{"still", mgl32.Vec2{}, mgl32.Vec2{}},
+	}
+	for _, test := range tests {
+		got := limitAngle(test.in, 20, 5)
+		if !near(got.X(), test.want.X()) || !near(got.Y(), test.want.Y()) {
+			t.Errorf("%v: limitAngle(%v) = %v, want %v", test.name, test.in, got, test.want)
+		}
+	}
+}
+
+func TestBallSpeedUp(t *testing.T) {
+	cfg := DefaultBall
+	tests := []struct {
+		name  string
+		y     float32
+		speed float32
+		want  float32
+	}{
+		{"over time", 300, 400, 400 + cfg.SpeedPerSecond*0.5},
+		{"new row", 0, 400, 400 + cfg.SpeedPerRow + cfg.SpeedPerSecond*0.5},
+		{"max speed", 0, cfg.MaxSpeed - 1, cfg.MaxSpeed},
+		{"over max speed", 300, cfg.MaxSpeed + 100, cfg.MaxSpeed},
+	}
+	for _, test := range tests {
+		g := newTestGame()
+		ball := g.Balls[0]
+		ball.Stuck = false
+		ball.Position = mgl32.Vec2{400, test.y}
+		ball.Velocity = mgl32.Vec2{0.6, -0.8}.Mul(test.speed)
+		g.updateBall(ball, 0.5, ball.Velocity)
+
+		if got := ball.Velocity.Len(); !near(got, test.want) {
+			t.Errorf("%v: speed %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	// A stuck ball waits at its launch speed.
+	g := newTestGame()
+	ball := g.Balls[0]
+	before := ball.Velocity
+	g.updateBall(ball, 0.5, before)
+	if ball.Velocity != before {
+		t.Fatalf("stuck ball sped up to %v", ball.Velocity)
+	}
+}
+
+func TestLoopNudge(t *testing.T) {
+	g := newTestGame()
+	g.BallConfig.SpeedPerSecond = 0
+	ball := g.Balls[0]
+	ball.Stuck = false
+	ball.Position = mgl32.Vec2{400, 300}
+	state := g.balls[ball]
+	angle := func() float32 {
+		return mgl32.RadToDeg(float32(math.Atan2(float64(ball.Velocity.Y()), float64(ball.Velocity.X()))))
+	}
+
+	// Each bounce flips the velocity, the last allowed one brings the nudge.
+	ball.Velocity = mgl32.Vec2{-300, -300}
+	for i := 1; i < g.BallConfig.LoopBounces; i++ {
+		before := ball.Velocity
+		ball.Velocity = mgl32.Vec2{-before.X(), before.Y()}
+		g.updateBall(ball, 0.01, before)
+	}
+	if state.bounces != g.BallConfig.LoopBounces-1 || state.nudges != 0 {
+		t.Fatalf("bounces %v, nudges %v", state.bounces, state.nudges)
+	}
+
+	// The ball now goes up and left at 135 degrees.
+	before := ball.Velocity
+	ball.Velocity = mgl32.Vec2{-before.X(), before.Y()}
+	was := float32(-135)
+	g.updateBall(ball, 0.01, before)
+	if state.bounces != 0 || state.nudges != 1 {
+		t.Fatalf("bounces %v, nudges %v after the nudge", state.bounces, state.nudges)
+	}
+	if turned := angle() - was; !near(turned, g.BallConfig.NudgeAngle) {
+		t.Fatalf("nudged by %v degrees", turned)
+	}
+
+	// The next nudge turns the other way.
+	state.bounces = g.BallConfig.LoopBounces
+	was = angle()
+	g.updateBall(ball, 0.01, ball.Velocity)
+	if turned := angle() - was; !near(turned, -g.BallConfig.NudgeAngle) {
+		t.Fatalf("second nudge by %v degrees", turned)
+	}
+
+	// Breaking a brick proves the ball is not looping.
+	state.bounces = 5
+	g.ballHitBrick(ball, true)
+	if state.bounces != 0 {
+		t.Fatalf("%v bounces after breaking a brick", state.bounces)
+	}
+}
+
+func TestMultiBall(t *testing.T) {
+	g := newTestGame()
+
+	// A ball waiting on the paddle is not split.
+	g.MultiBall(3, 20)
+	if len(g.Balls) != 1 {
+		t.Fatalf("stuck ball split into %v", len(g.Balls))
+	}
+
+	ball := g.Balls[0]
+	ball.Stuck = false
+	ball.Velocity = mgl32.Vec2{100, -350}
+	g.balls[ball].rows = 2
+	g.MultiBall(3, 20)
+	if len(g.Balls) != 3 {
+		t.Fatalf("split into %v balls", len(g.Balls))
+	}
+
+	speed := ball.Velocity.Len()
+	for i, split := range g.Balls[1:] {
+		if split.Position != ball.Position || !near(split.Velocity.Len(), speed) {
+			t.Errorf("ball %v at %v moving %v", i+1, split.Position, split.Velocity)
+		}
+		if split.Velocity == ball.Velocity {
+			t.Errorf("ball %v is not fanned out", i+1)
+		}
+		if state := g.balls[split]; state.rows != 2 || state.owner != g.Players[0] {
+			t.Errorf("ball %v has state %+v", i+1, state)
+		}
+	}
+	if g.Balls[1].Velocity == g.Balls[2].Velocity {
+		t.Error("split balls go the same way")
+	}
+}
+
+func TestMultiBallBrick(t *testing.T) {
+	g := newTestGame()
+	brick := g.Levels[0].Bricks[2]
+	brick.Kind = level.MultiBall
+	brick.HitPoints = 1
+
+	ball := g.Balls[0]
+	ball.Stuck = false
+	ball.Position = mgl32.Vec2{240, 40}
+	ball.Velocity = mgl32.Vec2{100, -350}
+	g.Update(0.01)
+
+	if !brick.Destroyed || len(g.Balls) != g.BallConfig.MultiBallCount {
+		t.Fatalf("brick destroyed %v, %v balls", brick.Destroyed, len(g.Balls))
+	}
+}
+
+func TestCollideBalls(t *testing.T) {
+	g := newTestGame()
+	clearBalls(g)
+	a := g.addBall(mgl32.Vec2{100, 300}, mgl32.Vec2{200, 0}, g.Players[0])
+	b := g.addBall(mgl32.Vec2{120, 300}, mgl32.Vec2{-200, 0}, g.Players[0])
+	g.collideBalls()
+
+	// Equal balls meeting head on trade velocities and end up touching.
+	if a.Velocity != (mgl32.Vec2{-200, 0}) || b.Velocity != (mgl32.Vec2{200, 0}) {
+		t.Fatalf("velocities %v and %v", a.Velocity, b.Velocity)
+	}
+	if d := b.Position.Sub(a.Position).Len(); !near(d, a.Radius+b.Radius) {
+		t.Fatalf("balls %v apart", d)
+	}
+
+	// Overlapping balls already moving apart are only separated.
+	a.Position = mgl32.Vec2{100, 300}
+	b.Position = mgl32.Vec2{100, 310}
+	g.collideBalls()
+	if a.Velocity != (mgl32.Vec2{-200, 0}) || b.Velocity != (mgl32.Vec2{200, 0}) {
+		t.Fatalf("velocities %v and %v after separating", a.Velocity, b.Velocity)
+	}
+	if d := b.Position.Sub(a.Position).Len(); !near(d, a.Radius+b.Radius) {
+		t.Fatalf("balls %v apart after separating", d)
+	}
+}
+
+func TestCollideDirective(t *testing.T) {
+	g := newTestGame()
+	g.State = GameActive
+	clearBalls(g)
+	a := g.addBall(mgl32.Vec2{300, 300}, mgl32.Vec2{200, -200}, g.Players[0])
+	b := g.addBall(mgl32.Vec2{310, 300}, mgl32.Vec2{-200, -200}, g.Players[0])
+
+	g.DoCollisions()
+	if a.Velocity.X() < 0 || b.Velocity.X() > 0 {
+		t.Fatal("balls collided without being asked to")
+	}
+
+	g.Levels[g.level].Collide = true
+	g.DoCollisions()
+	if a.Velocity.X() > 0 || b.Velocity.X() < 0 {
+		t.Fatalf("balls did not collide: %v and %v", a.Velocity, b.Velocity)
+	}
+}
+
+func TestLastBallLost(t *testing.T) {
+	g := newTestGame()
+	g.State = GameActive
+	clearBalls(g)
+	gone := g.addBall(mgl32.Vec2{400, 700}, mgl32.Vec2{100, 350}, g.Players[0])
+	left := g.addBall(mgl32.Vec2{400, 300}, mgl32.Vec2{100, -350}, g.Players[0])
+
+	g.Update(0.01)
+	if lives := g.Teams[0].Lives; lives != startLives {
+		t.Fatalf("lost a life with a ball still in play, %v left", lives)
+	}
+	if len(g.Balls) != 1 || g.Balls[0] != left || g.balls[gone] != nil {
+		t.Fatalf("balls in play %v", g.Balls)
+	}
+
+	left.Position = mgl32.Vec2{400, 700}
+	left.Velocity = mgl32.Vec2{100, 350}
+	if lost := g.removeLostBalls(); lost != g.Teams[0] || len(g.Balls) != 0 {
+		t.Fatalf("lost %+v, %v balls left", lost, len(g.Balls))
+	}
+
+	g.addBall(mgl32.Vec2{400, 700}, mgl32.Vec2{100, 350}, g.Players[0])
+	g.Update(0.01)
+	if lives := g.Teams[0].Lives; lives != startLives-1 {
+		t.Fatalf("%v lives after losing the last ball", lives)
+	}
+	if len(g.Balls) != 1 || !g.Balls[0].Stuck {
+		t.Fatal("no new ball served")
+	}
+}
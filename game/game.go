@@ -1,13 +1,31 @@
 package game
 
 import (
-	"github.com/go-gl/glfw/v3.3/glfw"
+	"fmt"
+	"image"
+	"log"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/go-gl/mathgl/mgl32"
 
+	"github.com/le-michael/breakout/camera"
+	"github.com/le-michael/breakout/input"
 	"github.com/le-michael/breakout/level"
+	"github.com/le-michael/breakout/loader"
+	"github.com/le-michael/breakout/netplay"
 	"github.com/le-michael/breakout/object"
 	"github.com/le-michael/breakout/resmgr"
+	"github.com/le-michael/breakout/score"
+	"github.com/le-michael/breakout/shader"
 	"github.com/le-michael/breakout/sprite"
+	"github.com/le-michael/breakout/text"
+	"github.com/le-michael/breakout/texture"
+	"github.com/le-michael/breakout/viewport"
+	"github.com/le-michael/breakout/watch"
 )
 
 type GameState int
@@ -16,185 +34,610 @@ const (
 	GameActive GameState = iota
 	GameMenu
 	GameWin
+	GameLoading
+	GameOver
+	GamePaused
+	// GameLoadFailed waits for the player to retry loading.
+	GameLoadFailed
 )
 
 var (
-	playerSize     = mgl32.Vec2{200, 20}
-	playerVelocity = float32(500.0)
+	startLives = 3
+
+	manifestFile = "assets.json"
+	watchDirs    = []string{"shaders", "textures", "levels"}
 
-	ballRadius   = float32(12.5)
-	ballVelocity = mgl32.Vec2{-100, -350}
+	// Time spent on GL uploads per frame while loading, so the loading
+	// screen keeps animating.
+	loadBudget = 8 * time.Millisecond
 )
 
 type Game struct {
 	State  GameState
-	Keys   []bool
 	Width  int
 	Height int
 
-	Levels []*level.GameLevel
-	level  uint32
-
-	Player *object.GameObject
-	Ball   *object.Ball
+	Input *input.Map
+	// InputFile is where rebound keys are saved. Bindings are not saved
+	// when it is empty.
+	InputFile string
+	menu      menu
+	// paused is the selected item of the pause overlay.
+	paused int
+	// Quit is set once the player asks to close the game.
+	Quit bool
+
+	Levels     []*level.GameLevel
+	levelFiles []string
+	level      uint32
+
+	Mode Mode
+	// Players each steer a paddle and defend the edge of their team.
+	Players      []*Player
+	Teams        []*Team
+	Paddle       PaddleConfig
+	paddleSprite *texture.Texture2D
+
+	BallConfig BallConfig
+	balls      map[*object.Ball]*ballState
+	// Balls are the balls in play. A life is only lost once all are gone.
+	Balls      []*object.Ball
+	ballSprite *texture.Texture2D
+	// split is set when a multiball brick broke this frame. The balls are
+	// split once the frame's collisions are done.
+	split bool
+
+	HighScores *score.Table
+	// levelTime is the time spent on the current level, for the clear bonus.
+	levelTime float32
+	// name is being typed in after a game that made the high-score table.
+	name   []rune
+	naming bool
+	rank   int
 
 	Renderer *sprite.SpriteRenderer
+	Text     *text.TextRenderer
+	shade    *texture.Texture2D
+	Viewport *viewport.Viewport
+
+	Camera    *camera.Camera
+	HUDCamera *camera.Camera
+
+	// frame holds the per-frame uniforms shared by every shader program.
+	frame *shader.UniformBuffer
+	time  float32
+
+	// net is the session of an online game, nil when playing locally.
+	net       *netplay.Session
+	netTime   float32
+	netLaunch bool
+
+	// SaveFile is where the game in progress is saved on quit and resumed
+	// from on start. Saving is off when it is empty.
+	SaveFile string
+
+	// DevMode watches the asset directories and hot reloads any change.
+	DevMode bool
+	watcher *watch.Watcher
+
+	loader *loader.Loader
+
+	// Errors shown on top of the screen, keyed by their source.
+	errors map[string]string
+
+	textures []string
+	shaders  []string
 }
 
+// Init loads what the loading screen needs and starts loading every other
+// asset in the background. The game starts once Update sees it is done.
 func (g *Game) Init() error {
-	// Load Shader
-	if err := resmgr.LoadShader("shaders/sprite.vert", "shaders/sprites.frg", "sprite"); err != nil {
+	if err := g.load(); err != nil {
 		return err
 	}
 
-	projection := mgl32.Ortho(0, float32(g.Width), float32(g.Height), 0, -1, 1)
-	spriteShader, err := resmgr.GetShader("sprite")
+	spriteShader, err := g.acquireShader("sprite")
 	if err != nil {
 		return err
 	}
 
-	spriteShader.SetInteger("image", 0, true)
-	spriteShader.SetMatrix4("projection", projection, false)
-
 	g.Renderer = sprite.New(spriteShader)
-
-	// Load Textures
-	if err := resmgr.LoadTexture("textures/background.jpg", false, "background"); err != nil {
+	g.frame = shader.NewUniformBuffer(shader.FrameBinding, &shader.FrameData{})
+	g.setupShader()
+
+	// Plain white texture for text and solid shapes
+	white := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	copy(white.Pix, []uint8{255, 255, 255, 255})
+	resmgr.LoadTextureFromImage(white, false, "white")
+	whiteTex, err := g.acquireTexture("white")
+	if err != nil {
 		return err
 	}
-	if err := resmgr.LoadTexture("textures/awesomeface.png", true, "face"); err != nil {
+	g.Text = text.New(g.Renderer, whiteTex)
+
+	// Translucent black for dimming the screen behind overlays
+	shade := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	copy(shade.Pix, []uint8{0, 0, 0, 160})
+	resmgr.LoadTextureFromImage(shade, true, "shade")
+	if g.shade, err = g.acquireTexture("shade"); err != nil {
 		return err
 	}
-	if err := resmgr.LoadTexture("textures/block.png", false, "block"); err != nil {
-		return err
+
+	if g.DevMode {
+		g.watcher, err = watch.New(resmgr.FS(), 500*time.Millisecond, watchDirs...)
+		if err != nil {
+			return fmt.Errorf("unable to watch assets: %v", err)
+		}
 	}
-	if err := resmgr.LoadTexture("textures/block_solid.png", false, "block_solid"); err != nil {
-		return err
+
+	g.loadHighScores()
+	g.loadInput()
+	return nil
+}
+
+// load compiles the shaders of the manifest, which the loading screen needs
+// itself, and starts loading every other asset in the background.
+func (g *Game) load() error {
+	for _, lvl := range g.Levels {
+		if lvl != nil {
+			lvl.Release()
+		}
 	}
-	if err := resmgr.LoadTexture("textures/paddle.png", false, "paddle"); err != nil {
+	g.Levels = nil
+
+	g.loader = loader.New(runtime.NumCPU())
+	manifest, err := g.loader.Manifest(manifestFile, g.Width, g.Height/2, func(i int, lvl *level.GameLevel) {
+		g.Levels[i] = lvl
+	})
+	if err != nil {
+		g.loader.Close()
+		g.loader = nil
 		return err
 	}
 
-	// Load Levels
-	one, err := level.Load("levels/one.lvl", g.Width, g.Height/2)
+	g.Levels = make([]*level.GameLevel, len(manifest.Levels))
+	g.levelFiles = g.levelFiles[:0]
+	for _, lvl := range manifest.Levels {
+		g.levelFiles = append(g.levelFiles, lvl.File)
+	}
+
+	g.State = GameLoading
+	return nil
+}
+
+func (g *Game) updateLoading() {
+	g.loader.Process(loadBudget)
+	if !g.loader.Done() {
+		return
+	}
+
+	err := g.loader.Err()
+	g.loader.Close()
+	g.loader = nil
 	if err != nil {
-		return err
+		g.loadFailed(fmt.Errorf("unable to load assets: %v", err))
+		return
 	}
-	g.Levels = append(g.Levels, one)
 
-	// Player
-	paddleSpr, err := resmgr.GetTexture("paddle")
+	if err := g.start(); err != nil {
+		g.loadFailed(fmt.Errorf("unable to start game: %v", err))
+		return
+	}
+	g.resume()
+	g.State = GameActive
+}
+
+func (g *Game) loadFailed(err error) {
+	log.Println(err)
+	g.errors["loading"] = err.Error()
+	g.State = GameLoadFailed
+}
+
+// retryLoading loads every asset again, picking up the ones fixed since the
+// last attempt.
+func (g *Game) retryLoading() {
+	delete(g.errors, "loading")
+	if err := g.load(); err != nil {
+		g.loadFailed(err)
+	}
+}
+
+func (g *Game) start() error {
+	// Players
+	paddleSpr, err := g.acquireTexture("paddle")
 	if err != nil {
 		return err
 	}
-
-	playerPos := mgl32.Vec2{float32(g.Width)/2 - playerSize.X()/2, float32(g.Height) - playerSize.Y()}
-	g.Player = object.NewGameObject(playerPos, playerSize, mgl32.Vec2{}, mgl32.Vec3{1, 1, 1}, paddleSpr)
+	g.paddleSprite = paddleSpr
 
 	// Ball
-	ballSpr, err := resmgr.GetTexture("face")
+	ballSpr, err := g.acquireTexture("face")
 	if err != nil {
 		return err
 	}
-	ballPos := playerPos.Add(mgl32.Vec2{playerSize.X()/2 - ballRadius, -ballRadius * 2})
-	g.Ball = object.NewBall(ballPos, ballRadius, ballVelocity, ballSpr)
+	g.ballSprite = ballSpr
 
+	g.newGame()
 	return nil
 }
 
+func (g *Game) acquireTexture(name string) (*texture.Texture2D, error) {
+	tex, err := resmgr.AcquireTexture(name)
+	if err != nil {
+		return nil, err
+	}
+	g.textures = append(g.textures, name)
+	return tex, nil
+}
+
+func (g *Game) acquireShader(name string) (*shader.Shader, error) {
+	program, err := resmgr.AcquireShader(name)
+	if err != nil {
+		return nil, err
+	}
+	g.shaders = append(g.shaders, name)
+	return program, nil
+}
+
+// Close releases every resource the game acquired.
+func (g *Game) Close() {
+	if g.loader != nil {
+		g.loader.Close()
+		g.loader = nil
+	}
+	for _, lvl := range g.Levels {
+		if lvl != nil {
+			lvl.Release()
+		}
+	}
+	g.Levels = nil
+
+	for _, name := range g.textures {
+		resmgr.ReleaseTexture(name)
+	}
+	for _, name := range g.shaders {
+		resmgr.ReleaseShader(name)
+	}
+	g.textures = nil
+	g.shaders = nil
+
+	if g.frame != nil {
+		g.frame.Delete()
+		g.frame = nil
+	}
+
+	if g.net != nil {
+		g.net.Close()
+		g.net = nil
+	}
+}
+
+func (g *Game) setupShader() {
+	g.Renderer.Shader.SetInteger("image", 0, true)
+}
+
+// setFrame updates the per-frame uniforms for a pass drawn with view.
+func (g *Game) setFrame(view mgl32.Mat4) {
+	g.frame.Update(&shader.FrameData{
+		Projection: g.Viewport.Projection(),
+		View:       view,
+		ScreenSize: mgl32.Vec2{float32(g.Viewport.Width), float32(g.Viewport.Height)},
+		Time:       g.time,
+	})
+}
+
+func (g *Game) Resize(fbWidth, fbHeight int) {
+	g.Viewport.Resize(fbWidth, fbHeight)
+	g.Viewport.Apply()
+}
+
 func (g *Game) Update(dt float32) {
-	g.Ball.Move(dt, g.Width)
+	if g.State != GamePaused {
+		g.time += dt
+	}
+
+	switch g.State {
+	case GameLoading:
+		g.updateLoading()
+		g.idleOnline()
+		return
+	case GameLoadFailed:
+		g.idleOnline()
+		return
+	}
+
+	if g.watcher != nil {
+		g.hotReload()
+	}
+
+	if g.net != nil {
+		g.updateOnline(dt)
+		return
+	}
+	g.simulate(dt)
+}
+
+// simulate advances the game in play by dt.
+func (g *Game) simulate(dt float32) {
+	if g.State != GameActive {
+		return
+	}
+	g.levelTime += dt
+
+	before := make([]mgl32.Vec2, len(g.Balls))
+	for i, ball := range g.Balls {
+		before[i] = ball.Velocity
+		ball.Move(dt, g.Width)
+	}
 
 	g.DoCollisions()
+	for i, ball := range g.Balls {
+		g.updateBall(ball, dt, before[i])
+	}
+	if g.split {
+		g.split = false
+		g.MultiBall(g.BallConfig.MultiBallCount, g.BallConfig.MultiBallAngle)
+	}
+
+	if lost := g.removeLostBalls(); len(g.Balls) == 0 {
+		lost.Score.BallLost()
+		lost.Lives--
+		if lost.Lives <= 0 {
+			g.gameOver(GameOver)
+			return
+		}
+		g.resetPlayers(g.server(lost))
+	}
+
+	if g.Levels[g.level].IsCompleted() {
+		for _, team := range g.Teams {
+			team.Score.LevelCleared(team.Lives, g.levelTime)
+		}
+		g.levelTime = 0
+		if int(g.level) == len(g.Levels)-1 {
+			g.gameOver(GameWin)
+			return
+		}
+		g.level++
+		g.resetPlayers(g.Players[0])
+	}
 }
 
 func (g *Game) ProcessInput(dt float32) {
-	if g.State == GameActive {
-		velocity := playerVelocity * dt
-		if g.Keys[glfw.KeyA] {
-			if g.Player.Position.X() >= 0 {
-				g.Player.Position = g.Player.Position.Add(mgl32.Vec2{-velocity, 0})
-				if g.Ball.Stuck {
-					g.Ball.Position = g.Ball.Position.Add(mgl32.Vec2{-velocity, 0})
-				}
-			}
-		}
-		if g.Keys[glfw.KeyD] {
-			if g.Player.Position.X() <= float32(g.Width)-playerSize.X() {
-				g.Player.Position = g.Player.Position.Add(mgl32.Vec2{velocity, 0})
-				if g.Ball.Stuck {
-					g.Ball.Position = g.Ball.Position.Add(mgl32.Vec2{velocity, 0})
-				}
-			}
+	defer g.Input.EndFrame()
+
+	if g.State == GameMenu {
+		g.menuInput()
+		return
+	}
+	if g.State == GamePaused {
+		g.pauseInput()
+		return
+	}
+	if g.Input.Pressed(input.Menu) && g.State != GameLoading && g.State != GameLoadFailed {
+		if g.net != nil {
+			g.goOffline(nil)
 		}
-		if g.Keys[glfw.KeySpace] {
-			g.Ball.Stuck = false
+		g.toggleMenu()
+		return
+	}
+	if g.Input.Pressed(input.Pause) && g.State == GameActive {
+		g.Pause()
+		return
+	}
+
+	// Online the paddles only move as the ticks come in.
+	if g.net != nil {
+		g.netLaunch = g.netLaunch || g.Input.Launched(input.PlayerControls...)
+		return
+	}
+
+	if g.State == GameActive {
+		for _, p := range g.Players {
+			g.movePaddle(p, dt)
 		}
 	}
 }
 
 func (g *Game) Render() {
-	if g.State == GameActive {
+	g.setFrame(g.Camera.View())
+	if g.State == GameActive || g.State == GamePaused {
 		g.Levels[g.level].Draw(g.Renderer)
-		g.Player.Draw(g.Renderer)
-		g.Ball.Draw(g.Renderer)
+		for _, p := range g.Players {
+			p.Paddle.Draw(g.Renderer)
+		}
+		for _, ball := range g.Balls {
+			ball.Draw(g.Renderer)
+		}
+	}
+
+	// Anything drawn from here on is HUD and ignores the world camera.
+	g.setFrame(g.HUDCamera.View())
+	switch g.State {
+	case GameLoading:
+		g.renderLoading()
+	case GameLoadFailed:
+		g.renderLoadFailed()
+	case GameActive:
+		g.renderScore()
+	case GameOver, GameWin:
+		g.renderGameOver()
+	case GamePaused:
+		g.renderScore()
+		g.renderPause()
+	case GameMenu:
+		g.renderMenu()
+	}
+	if len(g.errors) > 0 {
+		g.renderErrors()
+	}
+}
+
+func (g *Game) hotReload() {
+	files, err := g.watcher.Poll()
+	if err != nil {
+		g.errors["watch"] = err.Error()
+		return
+	}
+	delete(g.errors, "watch")
+
+	for _, file := range files {
+		if err := g.reloadFile(file); err != nil {
+			log.Println(err)
+			g.errors[file] = err.Error()
+		} else {
+			delete(g.errors, file)
+		}
+	}
+}
+
+func (g *Game) reloadFile(file string) error {
+	for i, levelFile := range g.levelFiles {
+		if path.Clean(levelFile) != file {
+			continue
+		}
+		if err := g.reloadLevel(i); err != nil {
+			return fmt.Errorf("unable to reload level %v: %v", file, err)
+		}
+		return nil
+	}
+
+	if err := resmgr.Reload(file); err != nil {
+		return err
+	}
+	// A relinked program starts over with default uniform values.
+	g.setupShader()
+	return nil
+}
+
+func (g *Game) reloadLevel(i int) error {
+	lvl, err := level.Load(resmgr.FS(), g.levelFiles[i], g.Width, g.Height/2)
+	if err != nil {
+		return err
+	}
+	g.Levels[i].Release()
+	g.Levels[i] = lvl
+	g.placeLevel(lvl)
+	return nil
+}
+
+func (g *Game) renderLoading() {
+	barSize := mgl32.Vec2{float32(g.Width) / 2, 20}
+	barPos := mgl32.Vec2{(float32(g.Width) - barSize.X()) / 2, (float32(g.Height) - barSize.Y()) / 2}
+
+	label := "LOADING"
+	scale := float32(3)
+	labelSize := text.Size(label, scale)
+	g.Text.Draw(label, mgl32.Vec2{(float32(g.Width) - labelSize.X()) / 2, barPos.Y() - labelSize.Y() - 8}, scale, mgl32.Vec3{1, 1, 1})
+
+	progress := mgl32.Vec2{barSize.X() * g.loader.Progress(), barSize.Y()}
+	g.Renderer.Draw(g.Text.White, barPos, barSize, 0, mgl32.Vec3{0.2, 0.2, 0.2})
+	g.Renderer.Draw(g.Text.White, barPos, progress, 0, mgl32.Vec3{1, 1, 1})
+}
+
+func (g *Game) renderLoadFailed() {
+	lines := []string{"LOADING FAILED", "PRESS ENTER TO RETRY"}
+	scale := float32(3)
+	y := float32(g.Height) / 2
+	for _, line := range lines {
+		size := text.Size(line, scale)
+		g.Text.Draw(line, mgl32.Vec2{(float32(g.Width) - size.X()) / 2, y - size.Y()}, scale, mgl32.Vec3{1, 1, 1})
+		y += size.Y() + 8
+		scale = 2
 	}
 }
 
+func (g *Game) renderErrors() {
+	files := make([]string, 0, len(g.errors))
+	for file := range g.errors {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	msgs := make([]string, 0, len(files))
+	for _, file := range files {
+		msgs = append(msgs, g.errors[file])
+	}
+
+	scale := float32(2)
+	padding := float32(8)
+	columns := int((float32(g.Width) - 2*padding) / text.Size(" ", scale).X())
+	msg := text.Wrap(strings.Join(msgs, "\n"), columns)
+
+	size := text.Size(msg, scale)
+	g.Renderer.Draw(g.Text.White, mgl32.Vec2{0, 0}, mgl32.Vec2{float32(g.Width), size.Y() + 2*padding}, 0, mgl32.Vec3{0.25, 0, 0})
+	g.Text.Draw(msg, mgl32.Vec2{padding, padding}, scale, mgl32.Vec3{1, 0.5, 0.5})
+}
+
 func (g *Game) DoCollisions() {
+	for _, ball := range g.Balls {
+		g.collideBall(ball)
+	}
+	if g.BallConfig.Collide || g.Levels[g.level].Collide {
+		g.collideBalls()
+	}
+}
+
+func (g *Game) collideBall(ball *object.Ball) {
 	for _, block := range g.Levels[g.level].Bricks {
 		if !block.Destroyed {
-			collision := CheckBallCollision(g.Ball, block)
+			collision := CheckBallCollision(ball, &block.GameObject)
 			if collision.Collide {
 				if !block.IsSolid {
-					block.Destroyed = true
+					broke := block.Hit()
+					g.balls[ball].owner.Team.Score.Brick(block.Kind, broke)
+					g.ballHitBrick(ball, broke)
+					g.split = g.split || (broke && block.Kind == level.MultiBall)
 				}
 				dir := collision.Direction
 				diff := collision.Difference
 				if dir == Right || dir == Left {
-					g.Ball.Velocity = mgl32.Vec2{-g.Ball.Velocity.X(), g.Ball.Velocity.Y()}
+					ball.Velocity = mgl32.Vec2{-ball.Velocity.X(), ball.Velocity.Y()}
 
-					penetration := g.Ball.Radius - mgl32.Abs(diff.X())
+					penetration := ball.Radius - mgl32.Abs(diff.X())
 					if dir == Left {
-						g.Ball.Position = mgl32.Vec2{g.Ball.Position.X() + penetration, g.Ball.Position.Y()}
+						ball.Position = mgl32.Vec2{ball.Position.X() + penetration, ball.Position.Y()}
 					} else {
-						g.Ball.Position = mgl32.Vec2{g.Ball.Position.X() - penetration, g.Ball.Position.Y()}
+						ball.Position = mgl32.Vec2{ball.Position.X() - penetration, ball.Position.Y()}
 					}
 				} else {
-					g.Ball.Velocity = mgl32.Vec2{g.Ball.Velocity.X(), -g.Ball.Velocity.Y()}
+					ball.Velocity = mgl32.Vec2{ball.Velocity.X(), -ball.Velocity.Y()}
 
-					penetration := g.Ball.Radius - mgl32.Abs(diff.Y())
+					penetration := ball.Radius - mgl32.Abs(diff.Y())
 					if dir == Up {
-						g.Ball.Position = mgl32.Vec2{g.Ball.Position.X(), g.Ball.Position.Y() - penetration}
+						ball.Position = mgl32.Vec2{ball.Position.X(), ball.Position.Y() - penetration}
 					} else {
-						g.Ball.Position = mgl32.Vec2{g.Ball.Position.X(), g.Ball.Position.Y() + penetration}
+						ball.Position = mgl32.Vec2{ball.Position.X(), ball.Position.Y() + penetration}
 					}
 				}
 			}
 		}
 	}
 
-	collision := CheckBallCollision(g.Ball, g.Player)
-	if !g.Ball.Stuck && collision.Collide {
-		centerBoard := g.Player.Position.X() + g.Player.Size.X()/2
-		distance := g.Ball.Position.X() + g.Ball.Radius - centerBoard
-		percentage := distance / (g.Player.Size.X() / 2)
-		strength := float32(2)
-		oldVelocity := g.Ball.Velocity
-		g.Ball.Velocity = mgl32.Vec2{
-			ballVelocity.X() * percentage * strength,
-			-1.0 * mgl32.Abs(g.Ball.Velocity.Y()),
+	for _, p := range g.Players {
+		collision := CheckBallCollision(ball, p.Paddle)
+		if !ball.Stuck && collision.Collide {
+			p.Team.Score.Paddle()
+			g.bounceOffPaddle(ball, p)
 		}
-		g.Ball.Velocity = g.Ball.Velocity.Normalize().Mul(oldVelocity.Len())
 	}
 }
 
 func New(width, height int) *Game {
 	return &Game{
 		State:  GameActive,
-		Keys:   make([]bool, 1024),
-		Width:  width,
-		Height: height,
+		Input:  input.New(),
+		Paddle: DefaultPaddle,
+
+		BallConfig: DefaultBall,
+		Width:      width,
+		Height:     height,
+		Viewport:   viewport.New(width, height),
+
+		errors: make(map[string]string),
+
+		Camera:    camera.New(float32(width), float32(height)),
+		HUDCamera: camera.NewScreen(float32(width), float32(height)),
 	}
 }
 
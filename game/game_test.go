@@ -0,0 +1,61 @@
+package game
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+
+	"github.com/le-michael/breakout/resmgr"
+)
+
+func TestRetryLoading(t *testing.T) {
+	fsys := fstest.MapFS{
+		manifestFile: {Data: []byte(`{"fonts": [{"name": "hud", "file": "fonts/hud.ttf"}]}`)},
+	}
+	defer resmgr.SetFS(resmgr.FS())
+	resmgr.SetFS(fsys)
+
+	g := newTestGame()
+	if err := g.load(); err != nil {
+		t.Fatal(err)
+	}
+	wait := func() {
+		deadline := time.Now().Add(5 * time.Second)
+		for g.State == GameLoading {
+			if time.Now().After(deadline) {
+				t.Fatal("still loading")
+			}
+			g.Update(0.01)
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// The font is missing, so loading stops and the loader is shut down.
+	wait()
+	if g.State != GameLoadFailed || g.loader != nil {
+		t.Fatalf("state %v, loader %v", g.State, g.loader)
+	}
+	if !strings.Contains(g.errors["loading"], "hud") {
+		t.Fatalf("error %q", g.errors["loading"])
+	}
+
+	// Enter loads everything again, finding the font this time. The game
+	// still cannot start without textures.
+	fsys["fonts/hud.ttf"] = &fstest.MapFile{Data: []byte("font")}
+	if !g.KeyPressed(glfw.KeyEnter) || g.State != GameLoading {
+		t.Fatalf("state %v after retrying", g.State)
+	}
+	if _, ok := g.errors["loading"]; ok {
+		t.Fatal("error kept while retrying")
+	}
+	wait()
+	if _, err := resmgr.GetFile("hud"); err != nil {
+		t.Fatal(err)
+	}
+	if g.State != GameLoadFailed || !strings.Contains(g.errors["loading"], "unable to start game") {
+		t.Fatalf("state %v, error %q", g.State, g.errors["loading"])
+	}
+}
@@ -0,0 +1,282 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/le-michael/breakout/input"
+	"github.com/le-michael/breakout/text"
+)
+
+// menu is the in-game menu, which doubles as the key rebinding screen. Its
+// navigation keys are fixed so it can never be locked out by a bad binding.
+type menu struct {
+	selected int
+	// capturing is set while waiting for the key to bind to the selected
+	// action.
+	capturing bool
+	message   string
+	previous  GameState
+}
+
+const (
+	menuMouse = iota + int(len(actionLabels))
+	menuSensitivity
+	menuResume
+	menuReset
+	menuQuit
+
+	menuItems
+)
+
+var actionLabels = [...]string{
+	input.MoveLeft:   "MOVE LEFT",
+	input.MoveRight:  "MOVE RIGHT",
+	input.Launch:     "LAUNCH",
+	input.Pause:      "PAUSE",
+	input.Menu:       "MENU",
+	input.MoveLeft2:  "P2 LEFT",
+	input.MoveRight2: "P2 RIGHT",
+	input.Launch2:    "P2 LAUNCH",
+}
+
+func (g *Game) loadInput() {
+	if g.InputFile == "" {
+		return
+	}
+	m, err := input.Load(g.InputFile)
+	if err != nil {
+		log.Println("Unable to load input config:", err)
+		g.errors["input"] = err.Error()
+		return
+	}
+	g.Input = m
+}
+
+func (g *Game) saveInput() {
+	if g.InputFile == "" {
+		return
+	}
+	if err := g.Input.Save(g.InputFile); err != nil {
+		log.Println(err)
+		g.errors["input"] = err.Error()
+		return
+	}
+	delete(g.errors, "input")
+}
+
+func (g *Game) toggleMenu() {
+	if g.State == GameMenu {
+		g.State = g.menu.previous
+		return
+	}
+	g.menu = menu{previous: g.State}
+	g.State = GameMenu
+}
+
+func (g *Game) menuKey(key glfw.Key) bool {
+	m := &g.menu
+	if m.capturing {
+		m.capturing = false
+		if key == glfw.KeyEscape {
+			m.message = ""
+			return true
+		}
+		g.bind(input.Action(m.selected), input.Key(key))
+		return true
+	}
+
+	switch key {
+	case glfw.KeyUp:
+		m.selected = (m.selected + menuItems - 1) % menuItems
+		m.message = ""
+	case glfw.KeyDown:
+		m.selected = (m.selected + 1) % menuItems
+		m.message = ""
+	case glfw.KeyEnter, glfw.KeyKPEnter:
+		g.selectMenuItem()
+	case glfw.KeyLeft:
+		g.adjustSensitivity(-0.25)
+	case glfw.KeyRight:
+		g.adjustSensitivity(0.25)
+	case glfw.KeyDelete, glfw.KeyBackspace:
+		g.unbind(input.Action(m.selected))
+	default:
+		if a, ok := g.Input.Bound(input.Key(key)); ok && a == input.Menu {
+			g.toggleMenu()
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// padMenuKeys lets a gamepad drive the menu like the keyboard does.
+var padMenuKeys = map[glfw.GamepadButton]glfw.Key{
+	glfw.ButtonDpadUp:    glfw.KeyUp,
+	glfw.ButtonDpadDown:  glfw.KeyDown,
+	glfw.ButtonA:         glfw.KeyEnter,
+	glfw.ButtonX:         glfw.KeyDelete,
+	glfw.ButtonDpadLeft:  glfw.KeyLeft,
+	glfw.ButtonDpadRight: glfw.KeyRight,
+}
+
+// menuInput handles mouse and gamepad buttons in the menu. Keys were already
+// handled by menuKey as they came in.
+func (g *Game) menuInput() {
+	for _, b := range g.Input.PressedBindings() {
+		if b.Device == input.Keyboard {
+			continue
+		}
+		if g.menu.capturing {
+			g.menu.capturing = false
+			g.bind(input.Action(g.menu.selected), b)
+			continue
+		}
+		if a, ok := g.Input.Bound(b); ok && a == input.Menu {
+			g.toggleMenu()
+			return
+		}
+		if b.Device == input.Gamepad {
+			if key, ok := padMenuKeys[glfw.GamepadButton(b.Code)]; ok {
+				g.menuKey(key)
+			}
+		}
+	}
+}
+
+func (g *Game) selectMenuItem() {
+	m := &g.menu
+	switch m.selected {
+	case menuMouse:
+		g.Input.Mouse.Mode = g.Input.Mouse.Mode.Next()
+		g.saveInput()
+	case menuSensitivity:
+		g.adjustSensitivity(0.25)
+	case menuResume:
+		g.toggleMenu()
+	case menuReset:
+		g.Input.Reset()
+		g.saveInput()
+		m.message = "DEFAULT KEYS RESTORED"
+	case menuQuit:
+		g.Quit = true
+	default:
+		m.capturing = true
+		m.message = ""
+	}
+}
+
+// adjustSensitivity changes the mouse sensitivity while it is selected, and
+// wraps around at the ends so Enter alone can reach every value.
+func (g *Game) adjustSensitivity(step float32) {
+	if g.menu.selected != menuSensitivity {
+		return
+	}
+	s := g.Input.Mouse.Sensitivity + step
+	if s > input.MaxSensitivity {
+		s = input.MinSensitivity
+	} else if s < input.MinSensitivity {
+		s = input.MaxSensitivity
+	}
+	g.Input.Mouse.Sensitivity = s
+	g.saveInput()
+}
+
+// CursorMoved handles a cursor position in window coordinates.
+func (g *Game) CursorMoved(x, y float64, windowWidth, windowHeight int) {
+	g.Input.Cursor(g.Viewport.ToVirtual(x, y, windowWidth, windowHeight).X())
+}
+
+// CursorMode returns the GLFW cursor mode for the current mouse mode. The
+// cursor is only hidden or captured while playing, so it is free again in
+// menus.
+func (g *Game) CursorMode() int {
+	if g.State != GameActive {
+		return glfw.CursorNormal
+	}
+	switch g.Input.Mouse.Mode {
+	case input.MouseAbsolute:
+		return glfw.CursorHidden
+	case input.MouseRelative:
+		return glfw.CursorDisabled
+	}
+	return glfw.CursorNormal
+}
+
+func (g *Game) bind(a input.Action, b input.Binding) {
+	if err := g.Input.Bind(a, b); err != nil {
+		g.menu.message = err.Error()
+		return
+	}
+	g.menu.message = ""
+	g.saveInput()
+}
+
+func (g *Game) unbind(a input.Action) {
+	if int(a) >= len(actionLabels) {
+		return
+	}
+	// Without a menu key there would be no way back to this screen.
+	if a == input.Menu {
+		g.menu.message = "THE MENU NEEDS A KEY"
+		return
+	}
+	g.Input.Unbind(a)
+	g.menu.message = ""
+	g.saveInput()
+}
+
+func (g *Game) renderMenu() {
+	white := mgl32.Vec3{1, 1, 1}
+	highlight := mgl32.Vec3{1, 0.8, 0.2}
+	scale := float32(2)
+	x := float32(g.Width) / 8
+	y := float32(g.Height) / 8
+
+	line := func(s string, color mgl32.Vec3) {
+		g.Text.Draw(s, mgl32.Vec2{x, y}, scale, color)
+		y += text.Size(s, scale).Y() + 4*scale
+	}
+
+	g.Text.Draw("MENU", mgl32.Vec2{x, y}, 5, white)
+	y += text.Size("MENU", 5).Y() + 8*scale
+
+	items := make([]string, menuItems)
+	for i, label := range actionLabels {
+		bindings := []string{}
+		for _, b := range g.Input.Bindings[input.Action(i)] {
+			bindings = append(bindings, b.String())
+		}
+		items[i] = fmt.Sprintf("%-12v %v", label, strings.Join(bindings, ", "))
+		if g.menu.capturing && i == g.menu.selected {
+			items[i] = fmt.Sprintf("%-12v PRESS A KEY OR BUTTON, ESCAPE CANCELS", label)
+		}
+	}
+	items[menuMouse] = fmt.Sprintf("%-12v %v", "MOUSE", strings.ToUpper(g.Input.Mouse.Mode.String()))
+	items[menuSensitivity] = fmt.Sprintf("%-12v <%.2f>", "SENSITIVITY", g.Input.Mouse.Sensitivity)
+	items[menuResume] = "RESUME"
+	items[menuReset] = "RESET KEYS"
+	items[menuQuit] = "QUIT"
+
+	for i, item := range items {
+		if i == menuMouse || i == menuResume {
+			y += 4 * scale
+		}
+		if i == g.menu.selected {
+			line("> "+item, highlight)
+		} else {
+			line("  "+item, white)
+		}
+	}
+
+	y += 4 * scale
+	if g.menu.message != "" {
+		line(g.menu.message, mgl32.Vec3{1, 0.5, 0.5})
+	}
+	line("UP/DOWN SELECT  ENTER CHANGE  DELETE CLEAR", mgl32.Vec3{0.6, 0.6, 0.6})
+}
@@ -0,0 +1,127 @@
+package game
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"log"
+
+	"github.com/le-michael/breakout/input"
+	"github.com/le-michael/breakout/netplay"
+)
+
+const (
+	// netTickRate is the fixed rate online games are simulated at, in
+	// ticks per second.
+	netTickRate = 60
+	// maxNetBacklog is how many ticks a stalled online game catches up on
+	// at most once the other player is back.
+	maxNetBacklog = 4
+)
+
+// Online plays the game in lockstep over session, which the peers have
+// agreed on a Mode for. Player n of the session steers paddle n. A game
+// still loading keeps the session idle and starts ticking once it is done.
+func (g *Game) Online(session *netplay.Session) {
+	g.net = session
+	g.netTime = 0
+	g.netLaunch = false
+}
+
+// Step advances an online game by one tick with the input of each player.
+// It reads no local state, so every peer computes the same game.
+func (g *Game) Step(inputs []netplay.Input) {
+	if g.State == GameOver || g.State == GameWin {
+		for _, in := range inputs {
+			if in.Launch {
+				g.restart()
+				return
+			}
+		}
+		return
+	}
+
+	dt := float32(1) / netTickRate
+	if g.State == GameActive {
+		for i, p := range g.Players {
+			if i < len(inputs) {
+				g.steerPaddle(p, inputs[i].Value(), inputs[i].Launch, 0, false, dt)
+			}
+		}
+	}
+	g.simulate(dt)
+}
+
+// Checksum hashes everything a save would keep, along with the state and
+// the loop counters of the balls, which is everything Step changes.
+func (g *Game) Checksum() uint32 {
+	content, err := json.Marshal(g.Snapshot())
+	if err != nil {
+		return 0
+	}
+	sum := crc32.Update(crc32.ChecksumIEEE(content), crc32.IEEETable, []byte{byte(g.State)})
+
+	counters := make([]byte, 8)
+	for _, ball := range g.Balls {
+		state := g.balls[ball]
+		binary.BigEndian.PutUint32(counters[0:], uint32(state.bounces))
+		binary.BigEndian.PutUint32(counters[4:], uint32(state.nudges))
+		sum = crc32.Update(sum, crc32.IEEETable, counters)
+	}
+	return sum
+}
+
+// idleOnline keeps the session alive while the game loads, so the other
+// player waits for a slow load instead of giving up. A failed load ends the
+// online game.
+func (g *Game) idleOnline() {
+	if g.net == nil {
+		return
+	}
+	if g.State == GameLoadFailed {
+		g.goOffline(errors.New("unable to load the game"))
+		return
+	}
+	if err := g.net.Idle(); err != nil {
+		g.goOffline(err)
+	}
+}
+
+// updateOnline runs as many ticks as fit in the time that passed. The
+// paddle follows the local input; the mouse is not used online.
+func (g *Game) updateOnline(dt float32) {
+	step := float32(1) / netTickRate
+	g.netTime += dt
+	for g.netTime >= step {
+		local := netplay.NewInput(g.Input.Axis(input.PlayerControls...), g.netLaunch)
+		if g.net.NeedsInput() {
+			g.netLaunch = false
+		}
+
+		stepped, err := g.net.Tick(local)
+		if err != nil {
+			g.goOffline(err)
+			return
+		}
+		if !stepped {
+			// Waiting on the other player.
+			if g.netTime > maxNetBacklog*step {
+				g.netTime = maxNetBacklog * step
+			}
+			return
+		}
+		g.netTime -= step
+	}
+}
+
+// goOffline ends the online game. Play goes on locally, with the paddle of
+// the other player on the player two keys.
+func (g *Game) goOffline(err error) {
+	if err != nil {
+		log.Println("Online game ended:", err)
+		g.errors["online"] = err.Error()
+	}
+	g.net.Close()
+	g.net = nil
+}
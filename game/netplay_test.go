@@ -0,0 +1,131 @@
+package game
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/le-michael/breakout/netplay"
+)
+
+// follow steers a player's paddle after the first ball and launches the
+// balls it holds, reading only the game it plays in.
+func follow(g *Game, player int) netplay.Input {
+	if len(g.Balls) == 0 || g.State != GameActive {
+		return netplay.Input{}
+	}
+	p := g.Players[player]
+	ball := g.Balls[0]
+	center := p.Paddle.Position.X() + p.Paddle.Size.X()/2
+	launch := ball.Stuck && g.balls[ball].owner == p
+	return netplay.NewInput((ball.Position.X()+ball.Radius-center)/50, launch)
+}
+
+// playOnline connects two games on localhost and plays them for ticks
+// ticks. change is called on the client game once, halfway through.
+func playOnline(t *testing.T, mode Mode, ticks uint32, change func(*Game)) (host, client *Game, hostErr, clientErr error) {
+	host, client = newTestGameMode(mode), newTestGameMode(mode)
+	host.State, client.State = GameActive, GameActive
+
+	l, err := netplay.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	accepted := make(chan *netplay.Session)
+	go func() {
+		cfg := netplay.DefaultConfig
+		cfg.Setup = mode.String()
+		s, err := l.Accept(host, cfg)
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- s
+	}()
+	cs, err := netplay.Dial(l.Addr().String(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := <-accepted
+	if hs == nil {
+		t.FailNow()
+	}
+	defer hs.Close()
+	defer cs.Close()
+	if setup, _ := ParseMode(cs.Setup); setup != mode {
+		t.Fatalf("client got mode %q", cs.Setup)
+	}
+
+	play := func(g *Game, s *netplay.Session, change func(*Game)) error {
+		deadline := time.Now().Add(20 * time.Second)
+		for s.Ticks() < ticks {
+			if change != nil && s.Ticks() == ticks/2 {
+				change(g)
+				change = nil
+			}
+			if _, err := s.Tick(follow(g, s.Player)); err != nil {
+				return err
+			}
+			if time.Now().After(deadline) {
+				return errors.New("took too long")
+			}
+			time.Sleep(50 * time.Microsecond)
+		}
+		return nil
+	}
+
+	errs := make(chan error)
+	go func() {
+		errs <- play(client, cs, change)
+	}()
+	hostErr = play(host, hs, nil)
+	clientErr = <-errs
+	return host, client, hostErr, clientErr
+}
+
+func TestOnlineLockstep(t *testing.T) {
+	for _, mode := range []Mode{Cooperative, Versus} {
+		host, client, hostErr, clientErr := playOnline(t, mode, 600, nil)
+		if hostErr != nil || clientErr != nil {
+			t.Fatalf("%v: %v, %v", mode, hostErr, clientErr)
+		}
+
+		want := host.Snapshot()
+		if got := client.Snapshot(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%v: client %+v, host %+v", mode, got, want)
+		}
+		if host.levelTime == 0 {
+			t.Fatalf("%v: nothing was played", mode)
+		}
+		moved := false
+		for _, ball := range host.Balls {
+			moved = moved || !ball.Stuck
+		}
+		if !moved && host.State == GameActive {
+			t.Fatalf("%v: no ball was launched", mode)
+		}
+	}
+}
+
+func TestOnlineDesync(t *testing.T) {
+	_, _, hostErr, clientErr := playOnline(t, Versus, 600, func(g *Game) {
+		g.Levels[g.level].Bricks[1].Destroyed = true
+	})
+	for _, err := range []error{hostErr, clientErr} {
+		desync := &netplay.DesyncError{}
+		if !errors.As(err, &desync) {
+			t.Fatalf("expected a desync, got %v", err)
+		}
+	}
+}
+
+func TestChecksumLoopCounters(t *testing.T) {
+	g := newTestGameMode(Versus)
+	sum := g.Checksum()
+	g.balls[g.Balls[0]].nudges++
+	if g.Checksum() == sum {
+		t.Fatal("checksum misses the loop counters")
+	}
+}
@@ -0,0 +1,138 @@
+package game
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/le-michael/breakout/object"
+)
+
+// PaddleConfig tunes how the paddle moves and how it returns the ball.
+type PaddleConfig struct {
+	Size mgl32.Vec2
+
+	// MaxSpeed is reached after MaxSpeed/Acceleration seconds of holding a
+	// direction. Letting go or reversing slows down at Deceleration.
+	MaxSpeed     float32
+	Acceleration float32
+	Deceleration float32
+
+	// Deflection is the horizontal speed given to a ball that hits the very
+	// edge of the paddle, scaled down towards the center.
+	Deflection float32
+	// English is the fraction of the paddle's own velocity passed on to the
+	// ball.
+	English float32
+	// Sticky makes the paddle catch the ball instead of bouncing it. The
+	// ball is launched again with the velocity it would have bounced with.
+	Sticky bool
+}
+
+var DefaultPaddle = PaddleConfig{
+	Size: mgl32.Vec2{200, 20},
+
+	MaxSpeed:     500,
+	Acceleration: 5000,
+	Deceleration: 7000,
+
+	Deflection: 200,
+	English:    0.25,
+}
+
+// movePaddle moves a paddle as its player's local input asks.
+func (g *Game) movePaddle(p *Player, dt float32) {
+	mouseX, mouse := float32(0), false
+	if p.Mouse {
+		center := p.Paddle.Position.X() + p.Paddle.Size.X()/2
+		mouseX, mouse = g.Input.PaddleX(center)
+	}
+	g.steerPaddle(p, g.Input.Axis(p.Controls...), g.Input.Launched(p.Controls...), mouseX, mouse, dt)
+}
+
+// steerPaddle accelerates a paddle towards the speed asked for by axis and
+// keeps it exactly within its lane. A mouse puts the paddle at mouseX
+// instead.
+func (g *Game) steerPaddle(p *Player, axis float32, launch bool, mouseX float32, mouse bool, dt float32) {
+	cfg := g.Paddle
+	paddle := p.Paddle
+	speed := paddle.Velocity.X()
+	target := axis * cfg.MaxSpeed
+
+	rate := cfg.Deceleration
+	if target != 0 && (speed == 0 || (target > 0) == (speed > 0)) && abs(target) > abs(speed) {
+		rate = cfg.Acceleration
+	}
+	speed = approach(speed, target, rate*dt)
+	x := paddle.Position.X() + speed*dt
+
+	// A mouse puts the paddle straight where it points, and its speed is
+	// whatever that took.
+	if mouse {
+		x = mouseX - paddle.Size.X()/2
+		if dt > 0 {
+			speed = (x - paddle.Position.X()) / dt
+		}
+	}
+
+	maxX := p.maxX - paddle.Size.X()
+	if x <= p.minX || x >= maxX {
+		x = mgl32.Clamp(x, p.minX, maxX)
+		speed = 0
+	}
+
+	offset := mgl32.Vec2{x - paddle.Position.X(), 0}
+	paddle.Position = paddle.Position.Add(offset)
+	paddle.Velocity = mgl32.Vec2{speed, 0}
+	for _, ball := range g.Balls {
+		if ball.Stuck && g.balls[ball].owner == p {
+			ball.Position = ball.Position.Add(offset)
+			ball.Stuck = !launch
+		}
+	}
+}
+
+// bounceOffPaddle returns the ball from a player's paddle. Where it hits
+// decides the angle, and the paddle's movement adds English on top.
+func (g *Game) bounceOffPaddle(ball *object.Ball, p *Player) {
+	cfg := g.Paddle
+	paddle := p.Paddle
+
+	center := paddle.Position.X() + paddle.Size.X()/2
+	offset := (ball.Position.X() + ball.Radius - center) / (paddle.Size.X() / 2)
+	offset = mgl32.Clamp(offset, -1, 1)
+
+	speed := ball.Velocity.Len()
+	velocity := mgl32.Vec2{
+		offset*cfg.Deflection + paddle.Velocity.X()*cfg.English,
+		-abs(ball.Velocity.Y()),
+	}
+	// Keep the ball out of the paddle so it cannot hit it twice.
+	y := paddle.Position.Y() - 2*ball.Radius
+	if p.Team.Top {
+		velocity = mgl32.Vec2{velocity.X(), -velocity.Y()}
+		y = paddle.Position.Y() + paddle.Size.Y()
+	}
+	ball.Velocity = limitAngle(velocity.Normalize().Mul(speed), g.BallConfig.MinAngle, g.BallConfig.MinVerticalAngle)
+	ball.Position = mgl32.Vec2{ball.Position.X(), y}
+
+	state := g.balls[ball]
+	state.bounces = 0
+	state.owner = p
+	if cfg.Sticky {
+		ball.Stuck = true
+	}
+}
+
+// approach moves value towards target by at most step.
+func approach(value, target, step float32) float32 {
+	if value < target {
+		return mgl32.Clamp(value+step, value, target)
+	}
+	return mgl32.Clamp(value-step, target, value)
+}
+
+func abs(x float32) float32 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,154 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/le-michael/breakout/input"
+)
+
+func near(a, b float32) bool {
+	return abs(a-b) < 0.01
+}
+
+// hold presses the keys that move the paddle along axis, or none for 0.
+func hold(g *Game, axis float32) {
+	g.Input.Release()
+	switch {
+	case axis < 0:
+		g.Input.Key(glfw.KeyLeft, glfw.Press)
+	case axis > 0:
+		g.Input.Key(glfw.KeyRight, glfw.Press)
+	}
+}
+
+func TestApproach(t *testing.T) {
+	tests := []struct {
+		value, target, step float32
+		want                float32
+	}{
+		{0, 10, 3, 3},
+		{0, 10, 20, 10},
+		{10, 0, 3, 7},
+		{10, 0, 20, 0},
+		{-5, 5, 3, -2},
+		{0, -10, 4, -4},
+		{5, 5, 1, 5},
+		{3, 8, 0, 3},
+	}
+	for _, test := range tests {
+		if got := approach(test.value, test.target, test.step); got != test.want {
+			t.Errorf("approach(%v, %v, %v) = %v, want %v", test.value, test.target, test.step, got, test.want)
+		}
+	}
+}
+
+func TestPaddleSpeed(t *testing.T) {
+	cfg := DefaultPaddle
+	tests := []struct {
+		name  string
+		speed float32
+		axis  float32
+		want  float32
+	}{
+		{"accelerate", 0, 1, cfg.Acceleration * 0.01},
+		{"accelerate left", 0, -1, -cfg.Acceleration * 0.01},
+		{"top speed", cfg.MaxSpeed - 1, 1, cfg.MaxSpeed},
+		{"let go", 100, 0, 100 - cfg.Deceleration*0.01},
+		{"stop", 50, 0, 0},
+		{"reverse", cfg.MaxSpeed, -1, cfg.MaxSpeed - cfg.Deceleration*0.01},
+	}
+	for _, test := range tests {
+		g := newTestGame()
+		p := g.Players[0]
+		p.Paddle.Velocity = mgl32.Vec2{test.speed, 0}
+		x := p.Paddle.Position.X()
+		hold(g, test.axis)
+		g.movePaddle(p, 0.01)
+
+		if got := p.Paddle.Velocity.X(); !near(got, test.want) {
+			t.Errorf("%v: speed %v, want %v", test.name, got, test.want)
+		}
+		if moved := p.Paddle.Position.X() - x; !near(moved, test.want*0.01) {
+			t.Errorf("%v: moved %v", test.name, moved)
+		}
+	}
+}
+
+func TestPaddleEdges(t *testing.T) {
+	tests := []struct {
+		name  string
+		axis  float32
+		mouse float32
+		want  float32
+	}{
+		{"left", -1, 0, 0},
+		{"right", 1, 0, 800 - DefaultPaddle.Size.X()},
+		{"mouse left", 0, -300, 0},
+		{"mouse right", 0, 2000, 800 - DefaultPaddle.Size.X()},
+		{"mouse", 0, 250, 250 - DefaultPaddle.Size.X()/2},
+	}
+	for _, test := range tests {
+		g := newTestGame()
+		p := g.Players[0]
+		hold(g, test.axis)
+		if test.mouse != 0 {
+			g.Input.Mouse.Mode = input.MouseAbsolute
+		}
+		for i := 0; i < 100; i++ {
+			if test.mouse != 0 {
+				g.Input.Cursor(test.mouse)
+			}
+			g.movePaddle(p, 0.05)
+		}
+		if x := p.Paddle.Position.X(); x != test.want {
+			t.Errorf("%v: paddle at %v, want %v", test.name, x, test.want)
+		}
+		if v := p.Paddle.Velocity.X(); v != 0 {
+			t.Errorf("%v: paddle still moving at %v", test.name, v)
+		}
+	}
+}
+
+func TestStuckBallFollowsPaddle(t *testing.T) {
+	g := newTestGame()
+	p := g.Players[0]
+	ball := g.Balls[0]
+	offset := ball.Position.X() - p.Paddle.Position.X()
+
+	hold(g, 1)
+	g.movePaddle(p, 0.05)
+	if !ball.Stuck || ball.Position.X()-p.Paddle.Position.X() != offset {
+		t.Fatalf("ball at %v, paddle at %v", ball.Position, p.Paddle.Position)
+	}
+	hold(g, 0)
+	g.Input.Key(glfw.KeySpace, glfw.Press)
+	g.movePaddle(p, 0.05)
+	if ball.Stuck {
+		t.Fatal("ball not launched")
+	}
+}
+
+func TestStickyPaddle(t *testing.T) {
+	for _, sticky := range []bool{false, true} {
+		g := newTestGame()
+		g.Paddle.Sticky = sticky
+		p := g.Players[0]
+		ball := g.Balls[0]
+		ball.Stuck = false
+		ball.Velocity = mgl32.Vec2{100, 350}
+		g.bounceOffPaddle(ball, p)
+
+		if ball.Stuck != sticky {
+			t.Errorf("sticky %v: ball stuck %v", sticky, ball.Stuck)
+		}
+		if ball.Velocity.Y() >= 0 {
+			t.Errorf("sticky %v: ball returned with %v", sticky, ball.Velocity)
+		}
+		if g.balls[ball].owner != p {
+			t.Errorf("sticky %v: paddle does not own the ball", sticky)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package game
+
+import (
+	"log"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/le-michael/breakout/input"
+	"github.com/le-michael/breakout/text"
+)
+
+const (
+	pauseResume = iota
+	pauseRestart
+	pauseQuit
+
+	pauseItems
+)
+
+var pauseLabels = [pauseItems]string{
+	pauseResume:  "RESUME",
+	pauseRestart: "RESTART LEVEL",
+	pauseQuit:    "QUIT TO MENU",
+}
+
+// Pause freezes the game. Nothing in Update advances while paused. Online
+// games cannot be paused, as the other player keeps going.
+func (g *Game) Pause() {
+	if g.State != GameActive || g.net != nil {
+		return
+	}
+	g.State = GamePaused
+	g.paused = 0
+}
+
+func (g *Game) Resume() {
+	if g.State == GamePaused {
+		g.State = GameActive
+	}
+}
+
+// FocusChanged pauses the game when the window loses focus. Keys held at
+// that moment are released elsewhere, so they are forgotten too.
+func (g *Game) FocusChanged(focused bool) {
+	if focused {
+		return
+	}
+	g.Input.Release()
+	g.Pause()
+}
+
+// pauseInput handles the actions that work while paused. Keys are handled
+// by pauseKey as they come in.
+func (g *Game) pauseInput() {
+	if g.Input.Pressed(input.Pause) || g.Input.Pressed(input.Menu) {
+		g.Resume()
+		return
+	}
+	for _, b := range g.Input.PressedBindings() {
+		if b.Device != input.Gamepad {
+			continue
+		}
+		if key, ok := padMenuKeys[glfw.GamepadButton(b.Code)]; ok {
+			g.pauseKey(key)
+		}
+	}
+}
+
+func (g *Game) pauseKey(key glfw.Key) bool {
+	switch key {
+	case glfw.KeyUp:
+		g.paused = (g.paused + pauseItems - 1) % pauseItems
+	case glfw.KeyDown:
+		g.paused = (g.paused + 1) % pauseItems
+	case glfw.KeyEnter, glfw.KeyKPEnter:
+		switch g.paused {
+		case pauseResume:
+			g.Resume()
+		case pauseRestart:
+			g.restartLevel()
+		case pauseQuit:
+			g.quitToMenu()
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// restartLevel starts the current level over, keeping score and lives.
+func (g *Game) restartLevel() {
+	if err := g.reloadLevel(int(g.level)); err != nil {
+		log.Println("Unable to restart level:", err)
+		g.errors["restart"] = err.Error()
+		return
+	}
+	delete(g.errors, "restart")
+
+	for _, team := range g.Teams {
+		team.Score.Paddle()
+	}
+	g.levelTime = 0
+	g.resetPlayers(g.Players[0])
+	g.State = GameActive
+}
+
+// quitToMenu gives up the game in progress as lost and opens the menu.
+// Leaving the menu shows the game over screen, where a new game starts. The
+// save is removed so the next start does not resume the game either.
+func (g *Game) quitToMenu() {
+	g.gameOver(GameOver)
+	if err := g.Autosave(); err != nil {
+		log.Println(err)
+		g.errors["save"] = err.Error()
+	}
+	g.toggleMenu()
+}
+
+func (g *Game) renderPause() {
+	// Dim the frozen game underneath.
+	g.Renderer.Draw(g.shade, mgl32.Vec2{0, 0}, mgl32.Vec2{float32(g.Width), float32(g.Height)}, 0, mgl32.Vec3{1, 1, 1})
+
+	white := mgl32.Vec3{1, 1, 1}
+	highlight := mgl32.Vec3{1, 0.8, 0.2}
+	y := float32(g.Height) / 3
+
+	line := func(s string, scale float32, color mgl32.Vec3) {
+		size := text.Size(s, scale)
+		g.Text.Draw(s, mgl32.Vec2{(float32(g.Width) - size.X()) / 2, y}, scale, color)
+		y += size.Y() + 4*scale
+	}
+
+	line("PAUSED", 5, white)
+	y += 16
+	for i, label := range pauseLabels {
+		if i == g.paused {
+			line("> "+label+" <", 2, highlight)
+		} else {
+			line(label, 2, white)
+		}
+	}
+}
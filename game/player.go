@@ -0,0 +1,230 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/le-michael/breakout/input"
+	"github.com/le-michael/breakout/level"
+	"github.com/le-michael/breakout/object"
+	"github.com/le-michael/breakout/score"
+)
+
+// Mode is how many play and whether they play together.
+type Mode int
+
+const (
+	SinglePlayer Mode = iota
+	// Cooperative puts two paddles side by side on the bottom edge. They
+	// share lives and score.
+	Cooperative
+	// Versus puts a paddle on the top and on the bottom edge, each
+	// defending its own side with lives and score of its own.
+	Versus
+)
+
+var modeNames = []string{
+	SinglePlayer: "single",
+	Cooperative:  "coop",
+	Versus:       "versus",
+}
+
+func (m Mode) String() string {
+	if m < 0 || int(m) >= len(modeNames) {
+		return fmt.Sprintf("Mode(%v)", int(m))
+	}
+	return modeNames[m]
+}
+
+func (m Mode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Mode) UnmarshalText(text []byte) error {
+	mode, err := ParseMode(string(text))
+	if err != nil {
+		return err
+	}
+	*m = mode
+	return nil
+}
+
+func ParseMode(s string) (Mode, error) {
+	for i, name := range modeNames {
+		if strings.EqualFold(name, s) {
+			return Mode(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown mode %q, expected one of %v", s, strings.Join(modeNames, ", "))
+}
+
+// Player is a paddle and the controls that steer it.
+type Player struct {
+	Paddle   *object.GameObject
+	Controls []input.Controls
+	// Mouse lets the mouse steer the paddle as well.
+	Mouse bool
+	Team  *Team
+
+	// The paddle stays between minX and maxX.
+	minX, maxX float32
+}
+
+// Team defends one edge of the playfield. Its players share lives and
+// score.
+type Team struct {
+	Name string
+	// Top teams defend the top edge, the others the bottom one.
+	Top   bool
+	Lives int
+	Score *score.Score
+}
+
+// setupPlayers creates the teams and paddles of the current mode, with a
+// fresh score and full lives.
+func (g *Game) setupPlayers() {
+	g.Teams, g.Players = g.newPlayers(g.Mode)
+	for _, lvl := range g.Levels {
+		g.placeLevel(lvl)
+	}
+}
+
+func (g *Game) newPlayers(mode Mode) ([]*Team, []*Player) {
+	width := float32(g.Width)
+	controls := input.PlayerControls
+	bottom := &Team{Name: "P1"}
+
+	var teams []*Team
+	var players []*Player
+	switch mode {
+	case Cooperative:
+		teams = []*Team{bottom}
+		players = []*Player{
+			g.newPlayer(bottom, 0, width/2, true, controls[0]),
+			g.newPlayer(bottom, width/2, width, false, controls[1]),
+		}
+	case Versus:
+		top := &Team{Name: "P2", Top: true}
+		teams = []*Team{bottom, top}
+		players = []*Player{
+			g.newPlayer(bottom, 0, width, true, controls[0]),
+			g.newPlayer(top, 0, width, false, controls[1]),
+		}
+	default:
+		teams = []*Team{bottom}
+		players = []*Player{g.newPlayer(bottom, 0, width, true, controls...)}
+	}
+
+	for _, team := range teams {
+		team.Lives = startLives
+		team.Score = score.New(score.DefaultRules)
+	}
+	return teams, players
+}
+
+func (g *Game) newPlayer(team *Team, minX, maxX float32, mouse bool, controls ...input.Controls) *Player {
+	return &Player{
+		Paddle:   object.NewGameObject(mgl32.Vec2{}, g.Paddle.Size, mgl32.Vec2{}, mgl32.Vec3{1, 1, 1}, g.paddleSprite),
+		Controls: controls,
+		Mouse:    mouse,
+		Team:     team,
+		minX:     minX,
+		maxX:     maxX,
+	}
+}
+
+// placeLevel moves the bricks to the middle of the screen in versus, so
+// both sides are as far from them.
+func (g *Game) placeLevel(lvl *level.GameLevel) {
+	if g.Mode == Versus {
+		lvl.MoveTo(float32(g.Height) / 4)
+	} else {
+		lvl.MoveTo(0)
+	}
+}
+
+// resetPlayers puts every paddle back in the middle of its lane, with as
+// many balls stuck to server as the level starts with. Every other ball is
+// launched mirrored.
+func (g *Game) resetPlayers(server *Player) {
+	for _, p := range g.Players {
+		x := (p.minX+p.maxX)/2 - p.Paddle.Size.X()/2
+		y := float32(g.Height) - p.Paddle.Size.Y()
+		if p.Team.Top {
+			y = 0
+		}
+		p.Paddle.Position = mgl32.Vec2{x, y}
+		p.Paddle.Velocity = mgl32.Vec2{}
+	}
+
+	g.Balls = nil
+	g.balls = make(map[*object.Ball]*ballState)
+	n := g.Levels[g.level].Balls
+	if n < 1 {
+		n = 1
+	}
+	radius := g.BallConfig.Radius
+	paddle := server.Paddle
+	for i := 0; i < n; i++ {
+		offset := (float32(i) - float32(n-1)/2) * radius * 3
+		pos := paddle.Position.Add(mgl32.Vec2{paddle.Size.X()/2 - radius + offset, -radius * 2})
+		vel := g.BallConfig.Velocity
+		if server.Team.Top {
+			pos = mgl32.Vec2{pos.X(), paddle.Size.Y()}
+			vel = mgl32.Vec2{vel.X(), -vel.Y()}
+		}
+		if i%2 == 1 {
+			vel = mgl32.Vec2{-vel.X(), vel.Y()}
+		}
+		g.addBall(pos, vel, server).Stuck = true
+	}
+}
+
+// team returns the team defending the top or bottom edge, or nil if no
+// one does.
+func (g *Game) team(top bool) *Team {
+	for _, team := range g.Teams {
+		if team.Top == top {
+			return team
+		}
+	}
+	return nil
+}
+
+// server returns the first player of a team, who serves after the team
+// lost a ball.
+func (g *Game) server(team *Team) *Player {
+	for _, p := range g.Players {
+		if p.Team == team {
+			return p
+		}
+	}
+	return g.Players[0]
+}
+
+// winner returns the team still alive, or the one with the higher score
+// once the last level is cleared. It is nil for a draw and in modes
+// without opponents.
+func (g *Game) winner() *Team {
+	if len(g.Teams) < 2 {
+		return nil
+	}
+	var best *Team
+	draw := false
+	for _, team := range g.Teams {
+		switch {
+		case team.Lives <= 0:
+			continue
+		case best == nil || team.Score.Points > best.Score.Points:
+			best, draw = team, false
+		case team.Score.Points == best.Score.Points:
+			draw = true
+		}
+	}
+	if draw {
+		return nil
+	}
+	return best
+}
@@ -0,0 +1,63 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestVersusLostBall(t *testing.T) {
+	g := newTestGameMode(Versus)
+	bottom, top := g.Teams[0], g.Teams[1]
+	if !top.Top || bottom.Top {
+		t.Fatalf("teams %+v, %+v", bottom, top)
+	}
+
+	// A ball leaving through the top costs the top team a life, and they
+	// serve the next one.
+	ball := g.Balls[0]
+	ball.Stuck = false
+	ball.Position = mgl32.Vec2{400, -2 * ball.Radius}
+	ball.Velocity = mgl32.Vec2{0, -300}
+	g.Update(0.1)
+
+	if top.Lives != startLives-1 || bottom.Lives != startLives {
+		t.Fatalf("lives %v top, %v bottom", top.Lives, bottom.Lives)
+	}
+	if len(g.Balls) != 1 || g.balls[g.Balls[0]].owner.Team != top {
+		t.Fatal("top team does not serve after losing the ball")
+	}
+	if v := g.Balls[0].Velocity.Y(); v <= 0 {
+		t.Fatalf("served ball heads up at %v", v)
+	}
+
+	top.Lives = 1
+	g.Balls[0].Stuck = false
+	g.Balls[0].Position = mgl32.Vec2{400, -2 * ball.Radius}
+	g.Balls[0].Velocity = mgl32.Vec2{0, -300}
+	g.Update(0.1)
+	if g.State != GameOver || g.winner() != bottom {
+		t.Fatalf("state %v, winner %+v", g.State, g.winner())
+	}
+}
+
+func TestCooperativeLanes(t *testing.T) {
+	g := newTestGameMode(Cooperative)
+	if len(g.Players) != 2 || len(g.Teams) != 1 {
+		t.Fatalf("%v players in %v teams", len(g.Players), len(g.Teams))
+	}
+
+	// Each paddle is held in its half however long it is pushed.
+	right := g.Players[1]
+	g.Input.Key(glfw.KeyLeft, glfw.Press)
+	for i := 0; i < 100; i++ {
+		g.movePaddle(right, 0.1)
+	}
+	if x := right.Paddle.Position.X(); x != float32(g.Width)/2 {
+		t.Fatalf("right paddle at %v", x)
+	}
+	if x := g.Players[0].Paddle.Position.X(); x != float32(g.Width)/4-g.Paddle.Size.X()/2 {
+		t.Fatalf("left paddle moved to %v", x)
+	}
+}
@@ -0,0 +1,240 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/le-michael/breakout/object"
+)
+
+// saveVersion is bumped whenever the layout of Save changes. Saves of any
+// other version are refused rather than half restored.
+const saveVersion = 3
+
+// Save is everything needed to resume a game in progress.
+type Save struct {
+	Version int  `json:"version"`
+	Mode    Mode `json:"mode"`
+
+	Level     int          `json:"level"`
+	LevelTime float32      `json:"levelTime"`
+	Bricks    []BrickState `json:"bricks"`
+
+	// Players and Teams are in the order the mode creates them.
+	Players []ObjectState `json:"players"`
+	Teams   []TeamState   `json:"teams"`
+	Balls   []BallState   `json:"balls"`
+}
+
+// BrickState is the state of a brick of the current level, in the order the
+// level file lists them.
+type BrickState struct {
+	Destroyed bool `json:"destroyed"`
+	HitPoints int  `json:"hitPoints"`
+}
+
+type ObjectState struct {
+	Position mgl32.Vec2 `json:"position"`
+	Velocity mgl32.Vec2 `json:"velocity"`
+}
+
+type BallState struct {
+	ObjectState
+	Stuck bool `json:"stuck"`
+	// Owner is the index of the player who touched the ball last.
+	Owner int `json:"owner"`
+	// Rows is how far into the wall the ball got, which it is not sped up
+	// for again.
+	Rows int `json:"rows"`
+}
+
+type TeamState struct {
+	Lives int        `json:"lives"`
+	Score ScoreState `json:"score"`
+}
+
+type ScoreState struct {
+	Points    int `json:"points"`
+	Combo     int `json:"combo"`
+	BestCombo int `json:"bestCombo"`
+}
+
+// Snapshot captures the state of the game in progress.
+func (g *Game) Snapshot() *Save {
+	s := &Save{
+		Version:   saveVersion,
+		Mode:      g.Mode,
+		Level:     int(g.level),
+		LevelTime: g.levelTime,
+	}
+	for _, brick := range g.Levels[g.level].Bricks {
+		s.Bricks = append(s.Bricks, BrickState{brick.Destroyed, brick.HitPoints})
+	}
+	owners := make(map[*Player]int)
+	for i, p := range g.Players {
+		s.Players = append(s.Players, ObjectState{p.Paddle.Position, p.Paddle.Velocity})
+		owners[p] = i
+	}
+	for _, team := range g.Teams {
+		s.Teams = append(s.Teams, TeamState{team.Lives, ScoreState{team.Score.Points, team.Score.Combo, team.Score.BestCombo}})
+	}
+	for _, ball := range g.Balls {
+		state := g.balls[ball]
+		s.Balls = append(s.Balls, BallState{ObjectState{ball.Position, ball.Velocity}, ball.Stuck, owners[state.owner], state.rows})
+	}
+	return s
+}
+
+// Restore puts the game back in the state of a snapshot. The levels must
+// already be loaded and match the ones the snapshot was taken from.
+func (g *Game) Restore(s *Save) error {
+	if s.Version != saveVersion {
+		return fmt.Errorf("unsupported save version %v, expected %v", s.Version, saveVersion)
+	}
+	if s.Level < 0 || s.Level >= len(g.Levels) {
+		return fmt.Errorf("save is on level %v, but there are only %v", s.Level+1, len(g.Levels))
+	}
+	bricks := g.Levels[s.Level].Bricks
+	if len(s.Bricks) != len(bricks) {
+		return fmt.Errorf("save has %v bricks on level %v, but the level has %v", len(s.Bricks), s.Level+1, len(bricks))
+	}
+	if len(s.Balls) == 0 {
+		return fmt.Errorf("save has no ball in play")
+	}
+	teams, players := g.newPlayers(s.Mode)
+	if len(s.Players) != len(players) || len(s.Teams) != len(teams) {
+		return fmt.Errorf("save has %v players in %v teams, but %v mode has %v in %v", len(s.Players), len(s.Teams), s.Mode, len(players), len(teams))
+	}
+	for _, ball := range s.Balls {
+		if ball.Owner < 0 || ball.Owner >= len(players) {
+			return fmt.Errorf("save has a ball of player %v, but there are only %v", ball.Owner+1, len(players))
+		}
+	}
+
+	g.Mode = s.Mode
+	g.newGame()
+	g.level = uint32(s.Level)
+	g.levelTime = s.LevelTime
+	for i, brick := range bricks {
+		brick.Destroyed = s.Bricks[i].Destroyed
+		brick.HitPoints = s.Bricks[i].HitPoints
+	}
+
+	for i, p := range g.Players {
+		p.Paddle.Position = s.Players[i].Position
+		p.Paddle.Velocity = s.Players[i].Velocity
+	}
+	for i, team := range g.Teams {
+		team.Lives = s.Teams[i].Lives
+		team.Score.Points = s.Teams[i].Score.Points
+		team.Score.Combo = s.Teams[i].Score.Combo
+		team.Score.BestCombo = s.Teams[i].Score.BestCombo
+	}
+
+	g.Balls = nil
+	g.balls = make(map[*object.Ball]*ballState)
+	for _, state := range s.Balls {
+		ball := g.addBall(state.Position, state.Velocity, g.Players[state.Owner])
+		ball.Stuck = state.Stuck
+		g.balls[ball].rows = state.Rows
+	}
+	return nil
+}
+
+// WriteSave writes a save file, replacing it in one step.
+func WriteSave(file string, s *Save) error {
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to write save: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return fmt.Errorf("unable to write save: %v", err)
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return fmt.Errorf("unable to write save: %v", err)
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("unable to write save: %v", err)
+	}
+	return nil
+}
+
+func ReadSave(file string) (*Save, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Save{}
+	if err := json.Unmarshal(content, s); err != nil {
+		return nil, fmt.Errorf("unable to parse save %v: %v", file, err)
+	}
+	if s.Version != saveVersion {
+		return nil, fmt.Errorf("unable to read save %v: unsupported version %v", file, s.Version)
+	}
+	return s, nil
+}
+
+// DefaultSaveFile returns where the game is saved, in the user config
+// directory.
+func DefaultSaveFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to find config directory: %v", err)
+	}
+	return filepath.Join(dir, "breakout", "save.json"), nil
+}
+
+// Autosave saves a game in progress to SaveFile. A finished game removes the
+// save instead, so the next start does not resume it. Online games are
+// neither saved nor allowed to touch the local save.
+func (g *Game) Autosave() error {
+	if g.SaveFile == "" || g.net != nil {
+		return nil
+	}
+
+	switch g.State {
+	case GameActive, GamePaused:
+		return WriteSave(g.SaveFile, g.Snapshot())
+	case GameOver, GameWin:
+		if err := os.Remove(g.SaveFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("unable to remove save: %v", err)
+		}
+	}
+	return nil
+}
+
+// resume restores the game saved in SaveFile, if there is one.
+func (g *Game) resume() {
+	if g.SaveFile == "" || g.net != nil {
+		return
+	}
+
+	s, err := ReadSave(g.SaveFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	// A game of another mode is left alone rather than switching modes
+	// behind the players' back.
+	if err == nil && s.Mode != g.Mode {
+		log.Printf("Not resuming the saved %v game in %v mode", s.Mode, g.Mode)
+		return
+	}
+	if err == nil {
+		err = g.Restore(s)
+	}
+	if err != nil {
+		log.Println("Unable to resume saved game:", err)
+		g.errors["save"] = err.Error()
+	}
+}
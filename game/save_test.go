@@ -0,0 +1,183 @@
+package game
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/le-michael/breakout/level"
+	"github.com/le-michael/breakout/netplay"
+	"github.com/le-michael/breakout/object"
+)
+
+// newTestGame builds a game with two levels of bricks and no GL resources.
+func newTestGame() *Game {
+	return newTestGameMode(SinglePlayer)
+}
+
+func newTestGameMode(mode Mode) *Game {
+	g := New(800, 600)
+	g.Mode = mode
+	for i := 0; i < 2; i++ {
+		lvl := &level.GameLevel{}
+		for j := 0; j < 4; j++ {
+			pos := mgl32.Vec2{float32(j) * 100, float32(i) * 50}
+			brick := object.NewBrick(pos, mgl32.Vec2{100, 50}, mgl32.Vec3{1, 1, 1}, nil, byte(j+1), 2)
+			brick.IsSolid = j == 0
+			lvl.Bricks = append(lvl.Bricks, brick)
+		}
+		g.Levels = append(g.Levels, lvl)
+	}
+	g.newGame()
+	return g
+}
+
+func TestSaveRoundTrip(t *testing.T) {
+	for _, mode := range []Mode{SinglePlayer, Cooperative, Versus} {
+		g := newTestGameMode(mode)
+		g.level = 1
+		g.levelTime = 42.5
+		g.Levels[1].Bricks[1].Destroyed = true
+		g.Levels[1].Bricks[2].HitPoints = 1
+		g.Players[0].Paddle.Position = mgl32.Vec2{320, 580}
+		g.Balls[0].Position = mgl32.Vec2{400, 300}
+		g.Balls[0].Velocity = mgl32.Vec2{150, -350}
+		g.Balls[0].Stuck = false
+		g.balls[g.Balls[0]].rows = 3
+		g.addBall(mgl32.Vec2{200, 250}, mgl32.Vec2{-150, -350}, g.Players[len(g.Players)-1])
+		g.Teams[0].Lives = 2
+		g.Teams[0].Score.Points = 1230
+		g.Teams[0].Score.Combo = 3
+		g.Teams[0].Score.BestCombo = 9
+
+		file := filepath.Join(t.TempDir(), "breakout", "save.json")
+		want := g.Snapshot()
+		if err := WriteSave(file, want); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadSave(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%v: read %+v, wrote %+v", mode, got, want)
+		}
+
+		restored := newTestGame()
+		if err := restored.Restore(got); err != nil {
+			t.Fatal(err)
+		}
+		if again := restored.Snapshot(); !reflect.DeepEqual(again, want) {
+			t.Fatalf("%v: restored %+v, saved %+v", mode, again, want)
+		}
+	}
+}
+
+func TestAutosave(t *testing.T) {
+	g := newTestGame()
+	g.SaveFile = filepath.Join(t.TempDir(), "save.json")
+	g.State = GameActive
+	g.Teams[0].Score.Points = 70
+	g.Levels[0].Bricks[3].Destroyed = true
+	if err := g.Autosave(); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed := newTestGame()
+	resumed.SaveFile = g.SaveFile
+	resumed.resume()
+	if len(resumed.errors) > 0 {
+		t.Fatal(resumed.errors)
+	}
+	if resumed.Teams[0].Score.Points != 70 || !resumed.Levels[0].Bricks[3].Destroyed {
+		t.Fatalf("resumed %+v", resumed.Snapshot())
+	}
+
+	// A finished game must not be resumed.
+	g.State = GameOver
+	if err := g.Autosave(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadSave(g.SaveFile); err == nil {
+		t.Fatal("save still exists after game over")
+	}
+}
+
+func TestAutosaveOnline(t *testing.T) {
+	g := newTestGame()
+	g.SaveFile = filepath.Join(t.TempDir(), "save.json")
+	g.State = GameActive
+	g.Teams[0].Score.Points = 70
+	if err := g.Autosave(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Neither playing nor losing online touches the local save.
+	g.net = &netplay.Session{}
+	g.Teams[0].Score.Points = 500
+	for _, state := range []GameState{GameActive, GameOver} {
+		g.State = state
+		if err := g.Autosave(); err != nil {
+			t.Fatal(err)
+		}
+		s, err := ReadSave(g.SaveFile)
+		if err != nil {
+			t.Fatalf("state %v: %v", state, err)
+		}
+		if s.Teams[0].Score.Points != 70 {
+			t.Fatalf("state %v: saved %v points", state, s.Teams[0].Score.Points)
+		}
+	}
+}
+
+func TestQuitToMenu(t *testing.T) {
+	g := newTestGame()
+	g.SaveFile = filepath.Join(t.TempDir(), "save.json")
+	g.State = GameActive
+	if err := g.Autosave(); err != nil {
+		t.Fatal(err)
+	}
+
+	g.Pause()
+	for _, key := range []glfw.Key{glfw.KeyDown, glfw.KeyDown, glfw.KeyEnter} {
+		g.KeyPressed(key)
+	}
+	if g.State != GameMenu || g.menu.previous != GameOver {
+		t.Fatalf("state %v, back to %v after quitting", g.State, g.menu.previous)
+	}
+	if _, err := ReadSave(g.SaveFile); err == nil {
+		t.Fatal("save still exists after quitting")
+	}
+
+	// Leaving the menu does not bring the game back.
+	g.toggleMenu()
+	if g.State != GameOver {
+		t.Fatalf("state %v after leaving the menu", g.State)
+	}
+}
+
+func TestRestoreMismatch(t *testing.T) {
+	g := newTestGame()
+	s := g.Snapshot()
+
+	tests := map[string]func(*Save){
+		"version": func(s *Save) { s.Version = saveVersion + 1 },
+		"level":   func(s *Save) { s.Level = len(g.Levels) },
+		"bricks":  func(s *Save) { s.Bricks = s.Bricks[1:] },
+		"balls":   func(s *Save) { s.Balls = nil },
+		"players": func(s *Save) { s.Mode = Versus },
+		"owner":   func(s *Save) { s.Balls[0].Owner = 1 },
+	}
+	for name, change := range tests {
+		bad := *s
+		bad.Bricks = append([]BrickState{}, s.Bricks...)
+		bad.Balls = append([]BallState{}, s.Balls...)
+		change(&bad)
+		if err := newTestGame().Restore(&bad); err == nil {
+			t.Errorf("%v: restore succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/le-michael/breakout/score"
+	"github.com/le-michael/breakout/text"
+)
+
+const (
+	maxNameLength = 12
+	defaultName   = "PLAYER"
+)
+
+func (g *Game) loadHighScores() {
+	file, err := score.File()
+	if err == nil {
+		g.HighScores, err = score.Load(file)
+	}
+	if err != nil {
+		// Keep the scores in memory rather than overwrite a file we could
+		// not read.
+		log.Println("Unable to load high scores:", err)
+		g.errors["scores"] = err.Error()
+		g.HighScores = score.NewTable("")
+	}
+}
+
+// newGame starts over from the first level with fresh scores.
+func (g *Game) newGame() {
+	g.setupPlayers()
+	g.level = 0
+	g.levelTime = 0
+	g.resetPlayers(g.Players[0])
+}
+
+func (g *Game) restart() {
+	for i := range g.Levels {
+		if err := g.reloadLevel(i); err != nil {
+			log.Println("Unable to restart:", err)
+			g.errors["restart"] = err.Error()
+			return
+		}
+	}
+	delete(g.errors, "restart")
+
+	g.newGame()
+	g.State = GameActive
+}
+
+// gameOver ends the game with state GameOver or GameWin and asks for a name
+// if the score made it into the high-score table. Only local games without
+// opponents make it into the table.
+func (g *Game) gameOver(state GameState) {
+	g.State = state
+	g.rank = -1
+	g.naming = g.net == nil && len(g.Teams) == 1 && g.HighScores.Qualifies(g.Teams[0].Score.Points)
+	g.name = g.name[:0]
+}
+
+func (g *Game) submitName() {
+	name := strings.TrimSpace(string(g.name))
+	if name == "" {
+		name = defaultName
+	}
+
+	g.naming = false
+	g.rank = g.HighScores.Add(score.Entry{
+		Name:   name,
+		Points: g.Teams[0].Score.Points,
+		Level:  int(g.level) + 1,
+		Date:   time.Now(),
+	})
+	if err := g.HighScores.Save(); err != nil {
+		log.Println(err)
+		g.errors["scores"] = err.Error()
+	}
+}
+
+// Char handles text input, which is only used for the high-score name.
+func (g *Game) Char(r rune) {
+	if !g.naming || len(g.name) >= maxNameLength {
+		return
+	}
+	if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == ' ') {
+		return
+	}
+	g.name = append(g.name, unicode.ToUpper(r))
+}
+
+// KeyPressed handles keys that act once per press rather than while held,
+// for text entry and menus. It reports whether the key was used up, in which
+// case it should not trigger an action as well.
+func (g *Game) KeyPressed(key glfw.Key) bool {
+	switch g.State {
+	case GameMenu:
+		return g.menuKey(key)
+	case GamePaused:
+		return g.pauseKey(key)
+	case GameLoadFailed:
+		if key != glfw.KeyEnter && key != glfw.KeyKPEnter {
+			return false
+		}
+		g.retryLoading()
+		return true
+	case GameOver, GameWin:
+	default:
+		return false
+	}
+
+	switch key {
+	case glfw.KeyBackspace:
+		if g.naming && len(g.name) > 0 {
+			g.name = g.name[:len(g.name)-1]
+		}
+	case glfw.KeyEnter, glfw.KeyKPEnter:
+		switch {
+		case g.naming:
+			g.submitName()
+		case g.net != nil:
+			// Online games restart on launch, which reaches both sides.
+			return false
+		default:
+			g.restart()
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func (g *Game) renderScore() {
+	scale := float32(2)
+	padding := float32(8)
+	white := mgl32.Vec3{1, 1, 1}
+
+	// With opponents each team's line is next to its own paddle.
+	for _, team := range g.Teams {
+		label := ""
+		y := padding
+		if g.Mode == Versus {
+			label = team.Name + " "
+			if team.Top {
+				y += g.Paddle.Size.Y()
+			} else {
+				y = float32(g.Height) - g.Paddle.Size.Y() - text.Size(label, scale).Y() - padding
+			}
+		}
+
+		g.Text.Draw(fmt.Sprintf("%vSCORE %v", label, team.Score.Points), mgl32.Vec2{padding, y}, scale, white)
+
+		lives := fmt.Sprintf("LIVES %v", team.Lives)
+		g.Text.Draw(lives, mgl32.Vec2{float32(g.Width) - text.Size(lives, scale).X() - padding, y}, scale, white)
+
+		if m := team.Score.Multiplier(); m > 1 {
+			combo := fmt.Sprintf("COMBO X%v", m)
+			g.Text.Draw(combo, mgl32.Vec2{(float32(g.Width) - text.Size(combo, scale).X()) / 2, y}, scale, mgl32.Vec3{1, 0.8, 0.2})
+		}
+	}
+}
+
+func (g *Game) renderGameOver() {
+	white := mgl32.Vec3{1, 1, 1}
+	highlight := mgl32.Vec3{1, 0.8, 0.2}
+	y := float32(g.Height) / 8
+
+	line := func(s string, scale float32, color mgl32.Vec3) {
+		size := text.Size(s, scale)
+		g.Text.Draw(s, mgl32.Vec2{(float32(g.Width) - size.X()) / 2, y}, scale, color)
+		y += size.Y() + 4*scale
+	}
+
+	if g.Mode == Versus {
+		title := "DRAW"
+		if winner := g.winner(); winner != nil {
+			title = winner.Name + " WINS"
+		}
+		line(title, 5, white)
+		for _, team := range g.Teams {
+			line(fmt.Sprintf("%v SCORE %v  BEST COMBO %v", team.Name, team.Score.Points, team.Score.BestCombo), 2, white)
+		}
+		line(g.playAgain(), 2, white)
+		return
+	}
+
+	title := "GAME OVER"
+	if g.State == GameWin {
+		title = "YOU WIN"
+	}
+	line(title, 5, white)
+	line(fmt.Sprintf("SCORE %v  BEST COMBO %v", g.Teams[0].Score.Points, g.Teams[0].Score.BestCombo), 2, white)
+
+	if g.naming {
+		line("NEW HIGH SCORE", 2, highlight)
+		line(fmt.Sprintf("NAME: %v_", string(g.name)), 2, white)
+		line("PRESS ENTER", 2, white)
+		return
+	}
+
+	line("HIGH SCORES", 2, highlight)
+	for i, entry := range g.HighScores.Entries {
+		color := white
+		if i == g.rank {
+			color = highlight
+		}
+		line(fmt.Sprintf("%2v. %-*v %7v", i+1, maxNameLength, entry.Name, entry.Points), 2, color)
+	}
+	line(g.playAgain(), 2, white)
+}
+
+func (g *Game) playAgain() string {
+	if g.net != nil {
+		return "LAUNCH TO PLAY AGAIN"
+	}
+	return "PRESS ENTER TO PLAY AGAIN"
+}
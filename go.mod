@@ -1,6 +1,6 @@
 module github.com/le-michael/breakout
 
-go 1.14
+go 1.16
 
 require (
 	github.com/go-gl/gl v0.0.0-20210315015930-ae072cafe09d
@@ -0,0 +1,179 @@
+package input
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+type Device int
+
+const (
+	Keyboard Device = iota
+	Mouse
+	// Gamepad buttons are shared by every connected gamepad.
+	Gamepad
+)
+
+// Binding is a single key or button that can trigger an action. In config
+// files it is written by name, such as "A", "Left", "Space", "Mouse1" or
+// "PadA".
+type Binding struct {
+	Device Device
+	Code   int
+}
+
+func Key(key glfw.Key) Binding {
+	return Binding{Keyboard, int(key)}
+}
+
+func MouseButton(button glfw.MouseButton) Binding {
+	return Binding{Mouse, int(button)}
+}
+
+func PadButton(button glfw.GamepadButton) Binding {
+	return Binding{Gamepad, int(button)}
+}
+
+func (b Binding) String() string {
+	switch b.Device {
+	case Keyboard:
+		if name, ok := keyNames[glfw.Key(b.Code)]; ok {
+			return name
+		}
+		return fmt.Sprintf("Key%v", b.Code)
+	case Mouse:
+		return fmt.Sprintf("Mouse%v", b.Code-int(glfw.MouseButton1)+1)
+	case Gamepad:
+		if name, ok := padButtonNames[glfw.GamepadButton(b.Code)]; ok {
+			return name
+		}
+		return fmt.Sprintf("Pad%v", b.Code)
+	}
+	return fmt.Sprintf("Device%v:%v", b.Device, b.Code)
+}
+
+func (b Binding) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+func (b *Binding) UnmarshalText(text []byte) error {
+	binding, err := ParseBinding(string(text))
+	if err != nil {
+		return err
+	}
+	*b = binding
+	return nil
+}
+
+// ParseBinding parses a binding name as written by Binding.String. Names
+// are not case sensitive.
+func ParseBinding(name string) (Binding, error) {
+	if key, ok := keysByName[strings.ToLower(name)]; ok {
+		return Key(key), nil
+	}
+
+	lower := strings.ToLower(name)
+	for button, padName := range padButtonNames {
+		if strings.ToLower(padName) == lower {
+			return PadButton(button), nil
+		}
+	}
+	if strings.HasPrefix(lower, "mouse") {
+		n, err := strconv.Atoi(lower[len("mouse"):])
+		if err == nil && n >= 1 && n <= int(glfw.MouseButtonLast-glfw.MouseButton1)+1 {
+			return MouseButton(glfw.MouseButton1 + glfw.MouseButton(n-1)), nil
+		}
+	}
+	if strings.HasPrefix(lower, "key") {
+		if n, err := strconv.Atoi(lower[len("key"):]); err == nil {
+			return Binding{Keyboard, n}, nil
+		}
+	}
+	return Binding{}, fmt.Errorf("unknown key or button %q", name)
+}
+
+var keyNames = map[glfw.Key]string{
+	glfw.KeySpace:        "Space",
+	glfw.KeyApostrophe:   "Apostrophe",
+	glfw.KeyComma:        "Comma",
+	glfw.KeyMinus:        "Minus",
+	glfw.KeyPeriod:       "Period",
+	glfw.KeySlash:        "Slash",
+	glfw.KeySemicolon:    "Semicolon",
+	glfw.KeyEqual:        "Equal",
+	glfw.KeyLeftBracket:  "LeftBracket",
+	glfw.KeyBackslash:    "Backslash",
+	glfw.KeyRightBracket: "RightBracket",
+	glfw.KeyGraveAccent:  "GraveAccent",
+	glfw.KeyEscape:       "Escape",
+	glfw.KeyEnter:        "Enter",
+	glfw.KeyTab:          "Tab",
+	glfw.KeyBackspace:    "Backspace",
+	glfw.KeyInsert:       "Insert",
+	glfw.KeyDelete:       "Delete",
+	glfw.KeyRight:        "Right",
+	glfw.KeyLeft:         "Left",
+	glfw.KeyDown:         "Down",
+	glfw.KeyUp:           "Up",
+	glfw.KeyPageUp:       "PageUp",
+	glfw.KeyPageDown:     "PageDown",
+	glfw.KeyHome:         "Home",
+	glfw.KeyEnd:          "End",
+	glfw.KeyCapsLock:     "CapsLock",
+	glfw.KeyPause:        "Pause",
+	glfw.KeyKPDecimal:    "KPDecimal",
+	glfw.KeyKPDivide:     "KPDivide",
+	glfw.KeyKPMultiply:   "KPMultiply",
+	glfw.KeyKPSubtract:   "KPSubtract",
+	glfw.KeyKPAdd:        "KPAdd",
+	glfw.KeyKPEnter:      "KPEnter",
+	glfw.KeyKPEqual:      "KPEqual",
+	glfw.KeyLeftShift:    "LeftShift",
+	glfw.KeyLeftControl:  "LeftControl",
+	glfw.KeyLeftAlt:      "LeftAlt",
+	glfw.KeyRightShift:   "RightShift",
+	glfw.KeyRightControl: "RightControl",
+	glfw.KeyRightAlt:     "RightAlt",
+}
+
+// Gamepad buttons are named after the Xbox layout, as GLFW's mappings are.
+var padButtonNames = map[glfw.GamepadButton]string{
+	glfw.ButtonA:           "PadA",
+	glfw.ButtonB:           "PadB",
+	glfw.ButtonX:           "PadX",
+	glfw.ButtonY:           "PadY",
+	glfw.ButtonLeftBumper:  "PadLB",
+	glfw.ButtonRightBumper: "PadRB",
+	glfw.ButtonBack:        "PadBack",
+	glfw.ButtonStart:       "PadStart",
+	glfw.ButtonGuide:       "PadGuide",
+	glfw.ButtonLeftThumb:   "PadLS",
+	glfw.ButtonRightThumb:  "PadRS",
+	glfw.ButtonDpadUp:      "PadUp",
+	glfw.ButtonDpadRight:   "PadRight",
+	glfw.ButtonDpadDown:    "PadDown",
+	glfw.ButtonDpadLeft:    "PadLeft",
+}
+
+var keysByName = make(map[string]glfw.Key)
+
+func init() {
+	// Letters, digits and function keys have consecutive codes.
+	for i := 0; i < 26; i++ {
+		keyNames[glfw.KeyA+glfw.Key(i)] = string(rune('A' + i))
+	}
+	for i := 0; i < 10; i++ {
+		keyNames[glfw.Key0+glfw.Key(i)] = string(rune('0' + i))
+		keyNames[glfw.KeyKP0+glfw.Key(i)] = fmt.Sprintf("KP%v", i)
+	}
+	for i := 0; i < 12; i++ {
+		keyNames[glfw.KeyF1+glfw.Key(i)] = fmt.Sprintf("F%v", i+1)
+	}
+
+	for key, name := range keyNames {
+		keysByName[strings.ToLower(name)] = key
+	}
+}
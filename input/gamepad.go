@@ -0,0 +1,91 @@
+package input
+
+import (
+	"log"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// PollGamepads reads the state of every connected gamepad. GLFW has no
+// events for gamepads, so it must be called once per frame, after
+// glfw.PollEvents. Gamepads can be plugged in and out at any time.
+func (m *Map) PollGamepads() {
+	buttons := make(map[Binding]bool)
+	m.stick = 0
+
+	for joy := glfw.Joystick1; joy <= glfw.JoystickLast; joy++ {
+		if !joy.IsGamepad() {
+			delete(m.pads, joy)
+			continue
+		}
+		state := joy.GetGamepadState()
+		if state == nil {
+			continue
+		}
+		m.pads[joy] = state
+
+		for i, action := range state.Buttons {
+			if action == glfw.Press {
+				buttons[PadButton(glfw.GamepadButton(i))] = true
+			}
+		}
+		// The stick pushed furthest wins when several pads are connected.
+		axis := int(glfw.AxisLeftX)
+		if x := m.deadzone(state.Axes[axis]); abs(x) > abs(m.stick) {
+			m.stick = x
+		}
+	}
+
+	// The buttons of every pad are merged, so a release on one pad does not
+	// cancel a button still held on another.
+	for b := range m.down {
+		if b.Device == Gamepad && !buttons[b] {
+			m.event(b, glfw.Release)
+		}
+	}
+	for b := range buttons {
+		if !m.down[b] {
+			m.event(b, glfw.Press)
+		}
+	}
+}
+
+// Joystick handles GLFW's joystick callback, for logging hotplug events.
+// The gamepad state itself is picked up by PollGamepads.
+func (m *Map) Joystick(joy glfw.Joystick, event glfw.PeripheralEvent) {
+	switch event {
+	case glfw.Connected:
+		if joy.IsGamepad() {
+			log.Printf("Gamepad %v connected: %v", int(joy-glfw.Joystick1)+1, joy.GetGamepadName())
+		} else {
+			log.Printf("Joystick %v connected without a gamepad mapping: %v", int(joy-glfw.Joystick1)+1, joy.GetName())
+		}
+	case glfw.Disconnected:
+		if _, ok := m.pads[joy]; ok {
+			log.Printf("Gamepad %v disconnected", int(joy-glfw.Joystick1)+1)
+		}
+	}
+}
+
+// deadzone rescales a stick axis so the deadzone reads 0 and the rest of
+// the travel still covers the whole range.
+func (m *Map) deadzone(x float32) float32 {
+	if abs(x) <= m.Deadzone {
+		return 0
+	}
+	scaled := (abs(x) - m.Deadzone) / (1 - m.Deadzone)
+	if scaled > 1 {
+		scaled = 1
+	}
+	if x < 0 {
+		return -scaled
+	}
+	return scaled
+}
+
+func abs(x float32) float32 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,439 @@
+package input
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// Action is something the player does, independent of the keys or buttons
+// bound to it.
+type Action int
+
+const (
+	MoveLeft Action = iota
+	MoveRight
+	Launch
+	Pause
+	Menu
+	// Player two steers with actions of their own.
+	MoveLeft2
+	MoveRight2
+	Launch2
+
+	actionCount
+)
+
+var actionNames = [actionCount]string{
+	MoveLeft:   "moveLeft",
+	MoveRight:  "moveRight",
+	Launch:     "launch",
+	Pause:      "pause",
+	Menu:       "menu",
+	MoveLeft2:  "moveLeft2",
+	MoveRight2: "moveRight2",
+	Launch2:    "launch2",
+}
+
+func (a Action) String() string {
+	if a < 0 || a >= actionCount {
+		return fmt.Sprintf("Action(%v)", int(a))
+	}
+	return actionNames[a]
+}
+
+func (a Action) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *Action) UnmarshalText(text []byte) error {
+	for i, name := range actionNames {
+		if strings.EqualFold(name, string(text)) {
+			*a = Action(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown action %q", text)
+}
+
+// Actions lists every action in display order.
+func Actions() []Action {
+	actions := make([]Action, actionCount)
+	for i := range actions {
+		actions[i] = Action(i)
+	}
+	return actions
+}
+
+// DefaultBindings gives player one WASD-style keys and the usual gamepad
+// buttons, and player two the arrow keys. A single player answers to both.
+func DefaultBindings() map[Action][]Binding {
+	return map[Action][]Binding{
+		MoveLeft:   {Key(glfw.KeyA), PadButton(glfw.ButtonDpadLeft)},
+		MoveRight:  {Key(glfw.KeyD), PadButton(glfw.ButtonDpadRight)},
+		Launch:     {Key(glfw.KeySpace), PadButton(glfw.ButtonA), MouseButton(glfw.MouseButtonLeft)},
+		Pause:      {Key(glfw.KeyP), PadButton(glfw.ButtonStart)},
+		Menu:       {Key(glfw.KeyEscape), PadButton(glfw.ButtonBack)},
+		MoveLeft2:  {Key(glfw.KeyLeft)},
+		MoveRight2: {Key(glfw.KeyRight)},
+		Launch2:    {Key(glfw.KeyUp)},
+	}
+}
+
+// Controls are the actions that steer one paddle.
+type Controls struct {
+	Left, Right, Launch Action
+	// Stick adds the gamepad stick to the keys.
+	Stick bool
+}
+
+// PlayerControls are the controls of player one and player two.
+var PlayerControls = []Controls{
+	{MoveLeft, MoveRight, Launch, true},
+	{MoveLeft2, MoveRight2, Launch2, false},
+}
+
+// Conflict is a binding shared by more than one action.
+type Conflict struct {
+	Binding Binding
+	Actions []Action
+}
+
+func (c Conflict) String() string {
+	names := make([]string, len(c.Actions))
+	for i, a := range c.Actions {
+		names[i] = a.String()
+	}
+	return fmt.Sprintf("%v is bound to %v", c.Binding, strings.Join(names, " and "))
+}
+
+// Map tracks the state of the keys and buttons and maps them to actions.
+type Map struct {
+	Bindings map[Action][]Binding
+
+	Mouse MouseConfig
+	// The cursor is tracked along x only, which is all the paddle needs.
+	cursorX     float32
+	cursorSet   bool
+	cursorMoved bool
+	mouseDelta  float32
+
+	// Deadzone is the part of the stick travel that is ignored, so worn
+	// sticks that do not center exactly leave the paddle alone.
+	Deadzone float32
+	pads     map[glfw.Joystick]*glfw.GamepadState
+	stick    float32
+
+	down map[Binding]bool
+	// pressed records the bindings that went down since the last EndFrame,
+	// so short taps between two frames are not lost.
+	pressed map[Binding]bool
+}
+
+// Axis returns how far the controls ask to move along x, from -1 to 1. The
+// movement actions count fully, the left stick of a gamepad proportionally.
+func (m *Map) Axis(controls ...Controls) float32 {
+	axis := float32(0)
+	for _, c := range controls {
+		if c.Stick {
+			axis += m.stick
+		}
+		if m.Down(c.Left) {
+			axis--
+		}
+		if m.Down(c.Right) {
+			axis++
+		}
+	}
+	if axis < -1 {
+		return -1
+	}
+	if axis > 1 {
+		return 1
+	}
+	return axis
+}
+
+func (m *Map) Key(key glfw.Key, action glfw.Action) {
+	m.event(Key(key), action)
+}
+
+func (m *Map) MouseButton(button glfw.MouseButton, action glfw.Action) {
+	m.event(MouseButton(button), action)
+}
+
+func (m *Map) event(b Binding, action glfw.Action) {
+	switch action {
+	case glfw.Press:
+		m.down[b] = true
+		m.pressed[b] = true
+	case glfw.Release:
+		delete(m.down, b)
+	}
+}
+
+// Down reports whether any binding of the action is held.
+func (m *Map) Down(a Action) bool {
+	for _, b := range m.Bindings[a] {
+		if m.down[b] {
+			return true
+		}
+	}
+	return false
+}
+
+// Pressed reports whether any binding of the action went down this frame.
+func (m *Map) Pressed(a Action) bool {
+	for _, b := range m.Bindings[a] {
+		if m.pressed[b] {
+			return true
+		}
+	}
+	return false
+}
+
+// Launched reports whether any of the controls launched this frame.
+func (m *Map) Launched(controls ...Controls) bool {
+	for _, c := range controls {
+		if m.Pressed(c.Launch) {
+			return true
+		}
+	}
+	return false
+}
+
+// PressedBindings returns the bindings that went down this frame, for
+// menus that work on raw buttons.
+func (m *Map) PressedBindings() []Binding {
+	bindings := make([]Binding, 0, len(m.pressed))
+	for b := range m.pressed {
+		bindings = append(bindings, b)
+	}
+	sort.Slice(bindings, func(i, j int) bool {
+		if bindings[i].Device != bindings[j].Device {
+			return bindings[i].Device < bindings[j].Device
+		}
+		return bindings[i].Code < bindings[j].Code
+	})
+	return bindings
+}
+
+// EndFrame forgets the presses of the frame that just ended.
+func (m *Map) EndFrame() {
+	for b := range m.pressed {
+		delete(m.pressed, b)
+	}
+}
+
+// Release forgets every held binding, for when the window loses focus and
+// the release events go elsewhere.
+func (m *Map) Release() {
+	for b := range m.down {
+		delete(m.down, b)
+	}
+	m.EndFrame()
+}
+
+// Bound returns the action a binding belongs to.
+func (m *Map) Bound(b Binding) (Action, bool) {
+	for _, a := range Actions() {
+		for _, other := range m.Bindings[a] {
+			if other == b {
+				return a, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Bind adds a binding to an action. A binding already used by another
+// action is refused.
+func (m *Map) Bind(a Action, b Binding) error {
+	if other, ok := m.Bound(b); ok {
+		if other == a {
+			return nil
+		}
+		return fmt.Errorf("%v is already bound to %v", b, other)
+	}
+	m.Bindings[a] = append(m.Bindings[a], b)
+	return nil
+}
+
+// Unbind removes every binding of an action. The action is kept with an
+// empty list, so a saved config does not bring its defaults back.
+func (m *Map) Unbind(a Action) {
+	m.Bindings[a] = []Binding{}
+}
+
+// Reset restores the default bindings and mouse settings.
+func (m *Map) Reset() {
+	m.Bindings = DefaultBindings()
+	m.Mouse = DefaultMouse
+}
+
+// Conflicts lists the bindings shared by several actions, sorted by name.
+func (m *Map) Conflicts() []Conflict {
+	actions := make(map[Binding][]Action)
+	for _, a := range Actions() {
+		for _, b := range m.Bindings[a] {
+			actions[b] = append(actions[b], a)
+		}
+	}
+
+	conflicts := []Conflict{}
+	for b, list := range actions {
+		if len(list) > 1 {
+			conflicts = append(conflicts, Conflict{b, list})
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Binding.String() < conflicts[j].Binding.String()
+	})
+	return conflicts
+}
+
+// DefaultMouse leaves the mouse off, as it fights with the keys for the
+// paddle.
+var DefaultMouse = MouseConfig{Mode: MouseOff, Sensitivity: 1}
+
+// config is the layout of the config file:
+//
+//	{
+//	  "bindings": {
+//	    "moveLeft": ["A", "Left"],
+//	    "launch":   ["Space", "Mouse1"]
+//	  },
+//	  "mouse": {"mode": "relative", "sensitivity": 1.5}
+//	}
+type config struct {
+	Bindings map[Action][]Binding `json:"bindings"`
+	Mouse    *MouseConfig         `json:"mouse,omitempty"`
+}
+
+// parseConfig reads a config file. Files written before the mouse settings
+// hold the bindings map alone, they are read as the bindings of a config.
+func parseConfig(content []byte) (config, error) {
+	c := config{}
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return c, err
+	}
+	_, bindings := fields["bindings"]
+	_, mouse := fields["mouse"]
+	if len(fields) > 0 && !bindings && !mouse {
+		return c, json.Unmarshal(content, &c.Bindings)
+	}
+	return c, json.Unmarshal(content, &c)
+}
+
+func (c config) taken(b Binding) bool {
+	for _, list := range c.Bindings {
+		for _, other := range list {
+			if other == b {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Save writes the bindings and mouse settings to a JSON config file. The
+// file is replaced in one step so a crash never leaves half a config behind.
+func (m *Map) Save(file string) error {
+	content, err := json.MarshalIndent(config{m.Bindings, &m.Mouse}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to save input config: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return fmt.Errorf("unable to save input config: %v", err)
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return fmt.Errorf("unable to save input config: %v", err)
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("unable to save input config: %v", err)
+	}
+	return nil
+}
+
+// DefaultFile returns where the input config lives, in the user config
+// directory.
+func DefaultFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to find config directory: %v", err)
+	}
+	return filepath.Join(dir, "breakout", "input.json"), nil
+}
+
+// Load reads the bindings and mouse settings from a config file. A missing
+// file gives the defaults; actions the file leaves out keep their default
+// bindings, except those the file gives to another action. Conflicting
+// bindings are an error.
+func Load(file string) (*Map, error) {
+	m := New()
+
+	content, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read input config: %v", err)
+	}
+
+	c, err := parseConfig(content)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse input config %v: %v", file, err)
+	}
+	for a, list := range c.Bindings {
+		m.Bindings[a] = list
+	}
+	for a, list := range m.Bindings {
+		if _, ok := c.Bindings[a]; ok {
+			continue
+		}
+		kept := []Binding{}
+		for _, b := range list {
+			if !c.taken(b) {
+				kept = append(kept, b)
+			}
+		}
+		m.Bindings[a] = kept
+	}
+	if c.Mouse != nil {
+		if err := c.Mouse.validate(); err != nil {
+			return nil, fmt.Errorf("invalid input config %v: %v", file, err)
+		}
+		m.Mouse = *c.Mouse
+	}
+
+	if conflicts := m.Conflicts(); len(conflicts) > 0 {
+		msgs := make([]string, len(conflicts))
+		for i, c := range conflicts {
+			msgs[i] = c.String()
+		}
+		return nil, fmt.Errorf("conflicting bindings in %v:\n  %v", file, strings.Join(msgs, "\n  "))
+	}
+	return m, nil
+}
+
+// New returns a map with the default bindings.
+func New() *Map {
+	return &Map{
+		Bindings: DefaultBindings(),
+		Mouse:    DefaultMouse,
+		Deadzone: 0.2,
+		pads:     make(map[glfw.Joystick]*glfw.GamepadState),
+		down:     make(map[Binding]bool),
+		pressed:  make(map[Binding]bool),
+	}
+}
@@ -0,0 +1,69 @@
+package input
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+func TestUnbindPersists(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "breakout", "input.json")
+
+	m := New()
+	m.Unbind(Launch)
+	// The freed key can go to another action.
+	if err := m.Bind(Pause, Key(glfw.KeySpace)); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Save(file); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(file + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("temporary file left behind: %v", err)
+	}
+
+	loaded, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Bindings[Launch]; len(got) != 0 {
+		t.Fatalf("cleared launch came back as %v", got)
+	}
+	if !reflect.DeepEqual(loaded.Bindings, m.Bindings) {
+		t.Fatalf("loaded %v, saved %v", loaded.Bindings, m.Bindings)
+	}
+}
+
+func TestLoadBindingsOnly(t *testing.T) {
+	// Configs from before the mouse settings hold nothing but bindings.
+	file := filepath.Join(t.TempDir(), "input.json")
+	content := `{"moveLeft": ["J"], "launch": ["Enter", "Mouse1"]}`
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Binding{Key(glfw.KeyEnter), MouseButton(glfw.MouseButton1)}
+	if got := m.Bindings[Launch]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("launch bound to %v, want %v", got, want)
+	}
+	if got := m.Bindings[MoveLeft]; !reflect.DeepEqual(got, []Binding{Key(glfw.KeyJ)}) {
+		t.Fatalf("move left bound to %v", got)
+	}
+	if !reflect.DeepEqual(m.Bindings[MoveRight], DefaultBindings()[MoveRight]) || m.Mouse != DefaultMouse {
+		t.Fatalf("defaults lost: %v, %+v", m.Bindings[MoveRight], m.Mouse)
+	}
+
+	if err := os.WriteFile(file, []byte(`{"jump": ["J"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(file); err == nil {
+		t.Fatal("loaded an unknown action")
+	}
+}
@@ -0,0 +1,105 @@
+package input
+
+import "fmt"
+
+type MouseMode int
+
+const (
+	// MouseOff leaves the paddle to keys and gamepads.
+	MouseOff MouseMode = iota
+	// MouseAbsolute centers the paddle under the cursor, which is hidden.
+	MouseAbsolute
+	// MouseRelative moves the paddle by how far the mouse moves, scaled by
+	// the sensitivity. The cursor is captured by the window.
+	MouseRelative
+
+	mouseModeCount
+)
+
+var mouseModeNames = [mouseModeCount]string{
+	MouseOff:      "off",
+	MouseAbsolute: "absolute",
+	MouseRelative: "relative",
+}
+
+func (m MouseMode) String() string {
+	if m < 0 || m >= mouseModeCount {
+		return fmt.Sprintf("MouseMode(%v)", int(m))
+	}
+	return mouseModeNames[m]
+}
+
+// Next cycles through the modes.
+func (m MouseMode) Next() MouseMode {
+	return (m + 1) % mouseModeCount
+}
+
+func (m MouseMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *MouseMode) UnmarshalText(text []byte) error {
+	for i, name := range mouseModeNames {
+		if name == string(text) {
+			*m = MouseMode(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown mouse mode %q", text)
+}
+
+type MouseConfig struct {
+	Mode        MouseMode `json:"mode"`
+	Sensitivity float32   `json:"sensitivity"`
+}
+
+const (
+	MinSensitivity = 0.25
+	MaxSensitivity = 4
+)
+
+func (c MouseConfig) validate() error {
+	if c.Sensitivity < MinSensitivity || c.Sensitivity > MaxSensitivity {
+		return fmt.Errorf("mouse sensitivity %v is outside %v to %v", c.Sensitivity, MinSensitivity, MaxSensitivity)
+	}
+	return nil
+}
+
+// Cursor records the cursor position along x, in the same units as the
+// paddle.
+func (m *Map) Cursor(x float32) {
+	if m.cursorSet {
+		m.mouseDelta += x - m.cursorX
+	}
+	m.cursorX = x
+	m.cursorSet = true
+	m.cursorMoved = true
+}
+
+// PaddleX returns where the mouse puts the center of a paddle that is
+// currently centered at x. It reports false when mouse control is off or
+// the mouse has not moved since the last call, so keys keep working.
+func (m *Map) PaddleX(x float32) (float32, bool) {
+	moved, delta := m.cursorMoved, m.mouseDelta
+	m.cursorMoved = false
+	m.mouseDelta = 0
+
+	if !moved {
+		return x, false
+	}
+	switch m.Mouse.Mode {
+	case MouseAbsolute:
+		return m.cursorX, true
+	case MouseRelative:
+		return x + delta*m.Mouse.Sensitivity, true
+	}
+	return x, false
+}
+
+// ResetCursor forgets the last cursor position, for when the cursor jumps
+// because it was captured or released.
+func (m *Map) ResetCursor() {
+	m.cursorSet = false
+	m.cursorMoved = false
+	m.mouseDelta = 0
+}
@@ -2,17 +2,31 @@ package level
 
 import (
 	"fmt"
-	"io/ioutil"
+	"io/fs"
+	"strconv"
+	"strings"
 
 	"github.com/go-gl/mathgl/mgl32"
 
 	"github.com/le-michael/breakout/object"
 	"github.com/le-michael/breakout/resmgr"
 	"github.com/le-michael/breakout/sprite"
+	"github.com/le-michael/breakout/texture"
 )
 
+// MultiBall bricks split the balls in play when broken.
+const MultiBall byte = 5
+
 type GameLevel struct {
-	Bricks []*object.GameObject
+	Bricks []*object.Brick
+	// Balls is how many balls the player starts with.
+	Balls int
+	// Collide makes the balls bounce off each other.
+	Collide bool
+	// Y is how far the bricks are moved down from the top of the screen.
+	Y float32
+
+	textures map[string]*texture.Texture2D
 }
 
 func (g *GameLevel) Draw(renderer *sprite.SpriteRenderer) {
@@ -30,30 +44,125 @@ func (g *GameLevel) IsCompleted() bool {
 	return true
 }
 
-func Load(file string, levelWidth int, levelHeight int) (*GameLevel, error) {
-	content, err := ioutil.ReadFile(file)
+// MoveTo moves every brick so the top of the level is at y.
+func (g *GameLevel) MoveTo(y float32) {
+	offset := mgl32.Vec2{0, y - g.Y}
+	for _, brick := range g.Bricks {
+		brick.Position = brick.Position.Add(offset)
+	}
+	g.Y = y
+}
+
+// Release gives back the textures the level acquired from resmgr.
+func (g *GameLevel) Release() {
+	for name := range g.textures {
+		resmgr.ReleaseTexture(name)
+	}
+	g.textures = nil
+}
+
+func (g *GameLevel) texture(name string) (*texture.Texture2D, error) {
+	if tex, ok := g.textures[name]; ok {
+		return tex, nil
+	}
+	tex, err := resmgr.AcquireTexture(name)
 	if err != nil {
 		return nil, err
 	}
+	g.textures[name] = tex
+	return tex, nil
+}
 
-	tileData := [][]byte{}
-	tileRow := []byte{}
-	for _, r := range content {
-		switch r {
-		case ' ':
+func Load(fsys fs.FS, file string, levelWidth int, levelHeight int) (*GameLevel, error) {
+	layout, err := Parse(fsys, file)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(layout, levelWidth, levelHeight)
+}
+
+// Layout is a parsed level file.
+type Layout struct {
+	Tiles   [][]byte
+	Balls   int
+	Collide bool
+}
+
+// Parse reads the tile data of a level file. Lines starting with # hold
+// directives: "# balls <n>" starts the level with n balls and "# collide"
+// makes them bounce off each other. It does not touch GL and is safe to call
+// from any goroutine.
+func Parse(fsys fs.FS, file string) (*Layout, error) {
+	content, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := &Layout{Tiles: [][]byte{}, Balls: 1}
+	for i, line := range strings.SplitAfter(string(content), "\n") {
+		if strings.HasPrefix(line, "#") {
+			if err := layout.directive(strings.Fields(line[1:])); err != nil {
+				return nil, fmt.Errorf("%v:%v: %v", file, i+1, err)
+			}
 			continue
-		case '\n':
-			tileData = append(tileData, tileRow)
-			tileRow = []byte{}
-		default:
-			tileRow = append(tileRow, r-'0')
 		}
+
+		tileRow := []byte{}
+		for _, r := range []byte(line) {
+			switch r {
+			case ' ':
+				continue
+			case '\n':
+				layout.Tiles = append(layout.Tiles, tileRow)
+			default:
+				tileRow = append(tileRow, r-'0')
+			}
+		}
+	}
+
+	// The first row sets the width of every brick.
+	switch {
+	case len(layout.Tiles) == 0:
+		return nil, fmt.Errorf("%v: no tiles", file)
+	case len(layout.Tiles[0]) == 0:
+		return nil, fmt.Errorf("%v: the first row has no tiles", file)
+	}
+	return layout, nil
+}
+
+func (l *Layout) directive(fields []string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	switch fields[0] {
+	case "balls":
+		if len(fields) != 2 {
+			return fmt.Errorf("usage: # balls <count>")
+		}
+		balls, err := strconv.Atoi(fields[1])
+		if err != nil || balls < 1 {
+			return fmt.Errorf("invalid ball count: %v", fields[1])
+		}
+		l.Balls = balls
+	case "collide":
+		if len(fields) != 1 {
+			return fmt.Errorf("usage: # collide")
+		}
+		l.Collide = true
+	default:
+		return fmt.Errorf("unknown directive: %v", fields[0])
 	}
+	return nil
+}
 
-	gameLevel, err := newLevel(tileData, levelWidth, levelHeight)
+func New(layout *Layout, levelWidth int, levelHeight int) (*GameLevel, error) {
+	gameLevel, err := newLevel(layout.Tiles, levelWidth, levelHeight)
 	if err != nil {
 		return nil, fmt.Errorf("unable to initalize level: %v", err)
 	}
+	gameLevel.Balls = layout.Balls
+	gameLevel.Collide = layout.Collide
 
 	return gameLevel, nil
 }
@@ -70,32 +179,37 @@ func newLevel(tileData [][]byte, levelWidth int, levelHeight int) (*GameLevel, e
 		2: {0.0, 0.7, 0.0},
 		3: {0.8, 0.8, 0.4},
 		4: {1.0, 0.5, 0.0},
+
+		MultiBall: {0.9, 0.2, 0.9},
 	}
 
-	gameLevel := &GameLevel{}
+	gameLevel := &GameLevel{
+		textures: make(map[string]*texture.Texture2D),
+	}
 
 	for i, row := range tileData {
 		for j, col := range row {
 			pos := mgl32.Vec2{unitWidth * float32(j), unitHeight * float32(i)}
 			size := mgl32.Vec2{unitWidth, unitHeight}
-			vel := mgl32.Vec2{0, 0}
 			switch col {
 			case 0:
 				continue
 			case 1:
-				tex, err := resmgr.GetTexture("block_solid")
+				tex, err := gameLevel.texture("block_solid")
 				if err != nil {
+					gameLevel.Release()
 					return nil, err
 				}
-				brick := object.NewGameObject(pos, size, vel, colors[col], tex)
+				brick := object.NewBrick(pos, size, colors[col], tex, col, 0)
 				brick.IsSolid = true
 				gameLevel.Bricks = append(gameLevel.Bricks, brick)
 			default:
-				tex, err := resmgr.GetTexture("block")
+				tex, err := gameLevel.texture("block")
 				if err != nil {
+					gameLevel.Release()
 					return nil, err
 				}
-				brick := object.NewGameObject(pos, size, vel, colors[col], tex)
+				brick := object.NewBrick(pos, size, colors[col], tex, col, 1)
 				gameLevel.Bricks = append(gameLevel.Bricks, brick)
 			}
 		}
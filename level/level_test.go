@@ -0,0 +1,45 @@
+package level
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParse(t *testing.T) {
+	fsys := fstest.MapFS{
+		"plain.lvl":  {Data: []byte("1 2\n0 3\n")},
+		"balls.lvl":  {Data: []byte("# balls 3\n# collide\n1 5\n4 0\n")},
+		"zero.lvl":   {Data: []byte("# balls 0\n1\n")},
+		"count.lvl":  {Data: []byte("# balls\n1\n")},
+		"args.lvl":   {Data: []byte("# collide all\n1\n")},
+		"bounce.lvl": {Data: []byte("1\n# bounce\n")},
+		"empty.lvl":  {Data: []byte("")},
+		"only.lvl":   {Data: []byte("# balls 2\n")},
+		"blank.lvl":  {Data: []byte("\n1 1\n")},
+	}
+
+	layout, err := Parse(fsys, "plain.lvl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Layout{Tiles: [][]byte{{1, 2}, {0, 3}}, Balls: 1}
+	if !reflect.DeepEqual(layout, want) {
+		t.Fatalf("parsed %+v, want %+v", layout, want)
+	}
+
+	layout, err = Parse(fsys, "balls.lvl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = &Layout{Tiles: [][]byte{{1, MultiBall}, {4, 0}}, Balls: 3, Collide: true}
+	if !reflect.DeepEqual(layout, want) {
+		t.Fatalf("parsed %+v, want %+v", layout, want)
+	}
+
+	for _, file := range []string{"zero.lvl", "count.lvl", "args.lvl", "bounce.lvl", "empty.lvl", "only.lvl", "blank.lvl", "missing.lvl"} {
+		if _, err := Parse(fsys, file); err == nil {
+			t.Errorf("%v: parsed", file)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package loader
+
+import (
+	"fmt"
+	"image"
+	"io/fs"
+	"sync"
+	"time"
+
+	"github.com/le-michael/breakout/level"
+	"github.com/le-michael/breakout/resmgr"
+	"github.com/le-michael/breakout/texture"
+)
+
+// Loader reads and decodes assets on worker goroutines and hands the GL
+// uploads back to the thread calling Process, which must be the thread that
+// owns the GL context.
+type Loader struct {
+	jobs chan func() func() error
+	quit chan struct{}
+
+	mu      sync.Mutex
+	uploads []func() error
+
+	// Levels are built from textures, so they wait until every other asset
+	// has been uploaded.
+	levels []func() error
+
+	total int
+	done  int
+	errs  []string
+
+	// manifest names the manifest file in errors, if one was loaded.
+	manifest string
+}
+
+func (l *Loader) Texture(tFile string, opts texture.Options, name string) {
+	l.queue(func() func() error {
+		rgba, err := resmgr.DecodeTexture(tFile)
+		return func() error {
+			if err != nil {
+				return fmt.Errorf("texture %v: %v", name, err)
+			}
+			resmgr.UploadTexture(tFile, rgba, opts, name)
+			return nil
+		}
+	})
+}
+
+func (l *Loader) File(file, name string) {
+	l.queue(func() func() error {
+		data, err := fs.ReadFile(resmgr.FS(), file)
+		return func() error {
+			if err != nil {
+				return fmt.Errorf("file %v: %v", name, err)
+			}
+			resmgr.AddFile(name, data)
+			return nil
+		}
+	})
+}
+
+// Manifest loads a manifest with resmgr.LoadManifest. Shaders are compiled
+// right away, since the loading screen needs them; everything else is
+// queued. loaded is called with the index and the finished level for each
+// level of the manifest. The returned error only holds the failures found
+// before anything was queued, the rest are reported by Err.
+func (l *Loader) Manifest(file string, levelWidth, levelHeight int, loaded func(int, *level.GameLevel)) (*resmgr.Manifest, error) {
+	l.manifest = file
+	return resmgr.LoadManifest(file, &manifestLoader{l, levelWidth, levelHeight, loaded, 0})
+}
+
+// manifestLoader queues the assets of a manifest on a Loader.
+type manifestLoader struct {
+	l                       *Loader
+	levelWidth, levelHeight int
+	loaded                  func(int, *level.GameLevel)
+	levels                  int
+}
+
+func (m *manifestLoader) Shader(sh resmgr.ShaderAsset) error {
+	return resmgr.LoadShaderVariant(sh.Vertex, sh.Fragment, sh.Geometry, sh.Defines, sh.Name)
+}
+
+func (m *manifestLoader) Texture(tex resmgr.TextureAsset) error {
+	m.l.Texture(tex.File, tex.Options, tex.Name)
+	return nil
+}
+
+func (m *manifestLoader) File(asset resmgr.FileAsset) error {
+	m.l.File(asset.File, asset.Name)
+	return nil
+}
+
+func (m *manifestLoader) Level(asset resmgr.FileAsset) error {
+	i := m.levels
+	m.levels++
+	m.l.Level(asset.File, m.levelWidth, m.levelHeight, func(lvl *level.GameLevel) {
+		m.loaded(i, lvl)
+	})
+	return nil
+}
+
+func (l *Loader) Image(rgba *image.RGBA, alpha bool, name string) {
+	l.queue(func() func() error {
+		return func() error {
+			resmgr.LoadTextureFromImage(rgba, alpha, name)
+			return nil
+		}
+	})
+}
+
+// Shader compiles a shader program; gFile may be empty.
+func (l *Loader) Shader(vFile, fFile, gFile string, defines map[string]string, name string) {
+	l.queue(func() func() error {
+		vSource, fSource, gSource, err := resmgr.ReadShader(vFile, fFile, gFile, defines)
+		return func() error {
+			if err != nil {
+				return fmt.Errorf("shader %v: %v", name, err)
+			}
+			return resmgr.UploadShader(vSource, fSource, gSource, defines, name)
+		}
+	})
+}
+
+// Level parses a level file and builds it once every texture is available.
+// loaded is called on the GL thread with the finished level.
+func (l *Loader) Level(file string, levelWidth, levelHeight int, loaded func(*level.GameLevel)) {
+	// Building the level counts as a step of its own.
+	l.total++
+	l.queue(func() func() error {
+		layout, err := level.Parse(resmgr.FS(), file)
+		return func() error {
+			if err != nil {
+				// The level is never built, skip that step.
+				l.done++
+				return err
+			}
+			l.levels = append(l.levels, func() error {
+				lvl, err := level.New(layout, levelWidth, levelHeight)
+				if err != nil {
+					return err
+				}
+				loaded(lvl)
+				return nil
+			})
+			return nil
+		}
+	})
+}
+
+func (l *Loader) queue(job func() func() error) {
+	l.total++
+	go func() {
+		select {
+		case l.jobs <- job:
+		case <-l.quit:
+		}
+	}()
+}
+
+func (l *Loader) worker() {
+	for {
+		select {
+		case job := <-l.jobs:
+			upload := job()
+			l.mu.Lock()
+			l.uploads = append(l.uploads, upload)
+			l.mu.Unlock()
+		case <-l.quit:
+			return
+		}
+	}
+}
+
+// Process runs pending GL uploads until there are none left or budget is
+// spent. It must be called from the GL thread, typically once per frame.
+func (l *Loader) Process(budget time.Duration) {
+	start := time.Now()
+	for time.Since(start) < budget {
+		l.mu.Lock()
+		if len(l.uploads) == 0 {
+			l.mu.Unlock()
+			break
+		}
+		upload := l.uploads[0]
+		l.uploads = l.uploads[1:]
+		l.mu.Unlock()
+
+		l.finish(upload)
+	}
+
+	// Once everything else is done, the only steps left are level builds.
+	for len(l.levels) > 0 && l.done+len(l.levels) == l.total && time.Since(start) < budget {
+		build := l.levels[0]
+		l.levels = l.levels[1:]
+		l.finish(build)
+	}
+}
+
+func (l *Loader) finish(step func() error) {
+	if err := step(); err != nil {
+		l.errs = append(l.errs, err.Error())
+	}
+	l.done++
+}
+
+// Progress returns the fraction of queued assets that are fully loaded.
+func (l *Loader) Progress() float32 {
+	if l.total == 0 {
+		return 1
+	}
+	return float32(l.done) / float32(l.total)
+}
+
+func (l *Loader) Done() bool {
+	return l.done == l.total
+}
+
+// Err returns every error encountered so far as one resmgr.ManifestError.
+func (l *Loader) Err() error {
+	if len(l.errs) == 0 {
+		return nil
+	}
+	return &resmgr.ManifestError{File: l.manifest, Errors: l.errs}
+}
+
+// Close stops the workers and drops whatever is still queued. Nothing may be
+// queued afterwards.
+func (l *Loader) Close() {
+	close(l.quit)
+}
+
+func New(workers int) *Loader {
+	l := &Loader{
+		jobs: make(chan func() func() error),
+		quit: make(chan struct{}),
+	}
+	for i := 0; i < workers; i++ {
+		go l.worker()
+	}
+	return l
+}
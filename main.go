@@ -1,29 +1,103 @@
 package main
 
 import (
+	"embed"
+	"flag"
 	"fmt"
 	"log"
+	"os"
 	"runtime"
+	"strings"
+	"sync"
 
 	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
 
+	"github.com/le-michael/breakout/assetfs"
+	"github.com/le-michael/breakout/capture"
 	"github.com/le-michael/breakout/game"
+	"github.com/le-michael/breakout/input"
+	"github.com/le-michael/breakout/netplay"
 	"github.com/le-michael/breakout/resmgr"
 )
 
 const (
 	windowWidth  = 800
 	windowHeight = 600
+
+	maxDeltaTime = float32(0.1)
 )
 
 var breakout = game.New(windowWidth, windowHeight)
 
+//go:embed assets.json shaders textures levels
+var embeddedAssets embed.FS
+
+var devMode = flag.Bool("dev", false, "watch shaders, textures and levels and hot reload them on change")
+
+var stickyPaddle = flag.Bool("sticky", false, "make the paddle catch the ball, which is launched again from the paddle")
+
+var gameMode = flag.String("mode", "single", "game mode: single, coop (two paddles at the bottom) or versus (one paddle at the top and one at the bottom)")
+
+var (
+	hostAddr = flag.String("host", "", "host an online coop or versus game on this address, like :7777")
+	joinAddr = flag.String("join", "", "join the online game hosted at this address, playing the host's mode")
+)
+
+var mods modList
+
+var (
+	captureDir   = flag.String("captures", "captures", "directory screenshots and frame captures are written to")
+	recordFrames = flag.Int("record-frames", 120, "number of frames captured by Shift+F12")
+
+	screenshotRequested bool
+	// screenshots tracks screenshots still being written.
+	screenshots sync.WaitGroup
+	recorder    *capture.Recorder
+)
+
+// Window placement to restore when leaving fullscreen.
+var windowed struct {
+	x, y, width, height int
+}
+
 func init() {
 	runtime.LockOSThread()
+	flag.Var(&mods, "mod", "directory or zip archive overlaid on the default assets, may be repeated")
 }
 
 func main() {
+	flag.Parse()
+	breakout.DevMode = *devMode
+	breakout.Paddle.Sticky = *stickyPaddle
+	if m, err := game.ParseMode(*gameMode); err != nil {
+		log.Fatalln(err)
+	} else {
+		breakout.Mode = m
+	}
+	if err := goOnline(); err != nil {
+		log.Fatalln("Unable to start online game:", err)
+	}
+	if saveFile, err := game.DefaultSaveFile(); err != nil {
+		log.Println("Saving disabled:", err)
+	} else {
+		breakout.SaveFile = saveFile
+	}
+	if inputFile, err := input.DefaultFile(); err != nil {
+		log.Println("Key bindings will not be saved:", err)
+	} else {
+		breakout.InputFile = inputFile
+	}
+	recorder = capture.NewRecorder(*captureDir)
+
+	fsys, err := assets()
+	if err != nil {
+		log.Fatalln("Unable to load assets:", err)
+	}
+	defer fsys.Close()
+	resmgr.SetFS(fsys)
+
 	if err := glfw.Init(); err != nil {
 		log.Fatalln("Failed to initalize glfw:", err)
 	}
@@ -48,15 +122,20 @@ func main() {
 	fmt.Println("OpenGL version", version)
 
 	window.SetKeyCallback(keyCallback)
+	window.SetCharCallback(charCallback)
+	window.SetMouseButtonCallback(mouseButtonCallback)
+	window.SetCursorPosCallback(cursorPosCallback)
+	window.SetFocusCallback(focusCallback)
+	glfw.SetJoystickCallback(joystickCallback)
 	window.SetFramebufferSizeCallback(framebufferSizeCallback)
 
-	gl.Viewport(0, 0, windowWidth, windowHeight)
 	gl.Enable(gl.BLEND)
 	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
 
 	if err := breakout.Init(); err != nil {
 		log.Fatalln("Unable to initalize breakout:", err)
 	}
+	breakout.Resize(window.GetFramebufferSize())
 
 	var deltaTime, lastFrame float32
 	deltaTime = 0
@@ -66,36 +145,213 @@ func main() {
 		currentFrame := float32(glfw.GetTime())
 		deltaTime = currentFrame - lastFrame
 		lastFrame = currentFrame
+		// A stalled frame, like while the window is dragged, must not
+		// teleport the ball.
+		if deltaTime > maxDeltaTime {
+			deltaTime = maxDeltaTime
+		}
 		glfw.PollEvents()
+		breakout.Input.PollGamepads()
+		applyCursorMode(window)
 
 		breakout.ProcessInput(deltaTime)
 
 		breakout.Update(deltaTime)
 
-		gl.ClearColor(0, 0, 0, 1)
-		gl.Clear(gl.COLOR_BUFFER_BIT)
+		breakout.Viewport.Clear(mgl32.Vec4{0, 0, 0, 1})
 		breakout.Render()
 
+		captureFrame()
+
 		window.SwapBuffers()
+
+		if breakout.Quit {
+			window.SetShouldClose(true)
+		}
+	}
+
+	screenshots.Wait()
+	if err := recorder.Wait(); err != nil {
+		log.Println("Unable to write captured frames:", err)
 	}
 
+	if err := breakout.Autosave(); err != nil {
+		log.Println(err)
+	}
+	breakout.Close()
+	for _, leak := range resmgr.Leaks() {
+		log.Println("Resource still alive at shutdown:", leak)
+	}
 	resmgr.Clear()
 }
 
+// goOnline hosts or joins an online game when asked to. It waits for the
+// other player before the window opens.
+func goOnline() error {
+	switch {
+	case *hostAddr != "":
+		if breakout.Mode == game.SinglePlayer {
+			return fmt.Errorf("online games need -mode coop or versus")
+		}
+		l, err := netplay.Listen(*hostAddr)
+		if err != nil {
+			return err
+		}
+		defer l.Close()
+
+		log.Println("Waiting for a player to join on", l.Addr())
+		cfg := netplay.DefaultConfig
+		cfg.Setup = breakout.Mode.String()
+		session, err := l.Accept(breakout, cfg)
+		if err != nil {
+			return err
+		}
+		breakout.Online(session)
+	case *joinAddr != "":
+		session, err := netplay.Dial(*joinAddr, breakout)
+		if err != nil {
+			return err
+		}
+		mode, err := game.ParseMode(session.Setup)
+		if err != nil {
+			session.Close()
+			return err
+		}
+		breakout.Mode = mode
+		breakout.Online(session)
+	}
+	return nil
+}
+
 func keyCallback(window *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
-	if key == glfw.KeyEscape && action == glfw.Press {
-		window.SetShouldClose(true)
+	if action == glfw.Press && (key == glfw.KeyF11 || (key == glfw.KeyEnter && mods&glfw.ModAlt != 0)) {
+		toggleFullscreen(window)
+		return
 	}
 
-	if key >= 0 && key < 1024 {
-		if action == glfw.Press {
-			breakout.Keys[key] = true
-		} else if action == glfw.Release {
-			breakout.Keys[key] = false
+	if key == glfw.KeyF12 && action == glfw.Press {
+		if mods&glfw.ModShift != 0 {
+			if err := recorder.Start(*recordFrames); err != nil {
+				log.Println("Unable to start recording:", err)
+			}
+		} else {
+			screenshotRequested = true
 		}
+		return
 	}
+
+	if action == glfw.Press && breakout.KeyPressed(key) {
+		return
+	}
+	breakout.Input.Key(key, action)
+}
+
+func mouseButtonCallback(window *glfw.Window, button glfw.MouseButton, action glfw.Action, mods glfw.ModifierKey) {
+	breakout.Input.MouseButton(button, action)
+}
+
+func charCallback(window *glfw.Window, char rune) {
+	breakout.Char(char)
+}
+
+func focusCallback(window *glfw.Window, focused bool) {
+	breakout.FocusChanged(focused)
+}
+
+func cursorPosCallback(window *glfw.Window, x, y float64) {
+	width, height := window.GetSize()
+	breakout.CursorMoved(x, y, width, height)
+}
+
+// applyCursorMode hides or captures the cursor when the game asks for a
+// different cursor mode.
+func applyCursorMode(window *glfw.Window) {
+	mode := breakout.CursorMode()
+	if window.GetInputMode(glfw.CursorMode) == mode {
+		return
+	}
+	window.SetInputMode(glfw.CursorMode, mode)
+	if glfw.RawMouseMotionSupported() {
+		raw := glfw.False
+		if mode == glfw.CursorDisabled {
+			raw = glfw.True
+		}
+		window.SetInputMode(glfw.RawMouseMotion, raw)
+	}
+	// Capturing moves the cursor, which must not move the paddle.
+	breakout.Input.ResetCursor()
+}
+
+func joystickCallback(joy glfw.Joystick, event glfw.PeripheralEvent) {
+	breakout.Input.Joystick(joy, event)
 }
 
 func framebufferSizeCallback(window *glfw.Window, width int, height int) {
-	gl.Viewport(0, 0, int32(width), int32(height))
+	breakout.Resize(width, height)
+}
+
+// captureFrame reads back the playfield for a pending screenshot or a frame
+// recording. It runs after rendering, while the frame is in the back buffer.
+func captureFrame() {
+	v := breakout.Viewport
+	if screenshotRequested {
+		screenshotRequested = false
+		img := capture.Read(v.X, v.Y, v.Width, v.Height)
+		screenshots.Add(1)
+		go func() {
+			defer screenshots.Done()
+			file, err := capture.Screenshot(*captureDir, img)
+			if err != nil {
+				log.Println("Unable to save screenshot:", err)
+				return
+			}
+			log.Println("Saved screenshot", file)
+		}()
+	}
+
+	recorder.Capture(v.X, v.Y, v.Width, v.Height)
+}
+
+func toggleFullscreen(window *glfw.Window) {
+	if window.GetMonitor() != nil {
+		window.SetMonitor(nil, windowed.x, windowed.y, windowed.width, windowed.height, 0)
+		return
+	}
+
+	windowed.x, windowed.y = window.GetPos()
+	windowed.width, windowed.height = window.GetSize()
+
+	monitor := glfw.GetPrimaryMonitor()
+	mode := monitor.GetVideoMode()
+	window.SetMonitor(monitor, 0, 0, mode.Width, mode.Height, mode.RefreshRate)
+}
+
+// modList holds the -mod flags, the last one given takes precedence.
+type modList []string
+
+func (m *modList) String() string {
+	return strings.Join(*m, ",")
+}
+
+func (m *modList) Set(value string) error {
+	*m = append(*m, value)
+	return nil
+}
+
+func assets() (assetfs.Overlay, error) {
+	layers := assetfs.Overlay{embeddedAssets}
+	for _, mod := range mods {
+		fsys, err := assetfs.Open(mod)
+		if err != nil {
+			layers.Close()
+			return nil, err
+		}
+		layers = append(assetfs.Overlay{fsys}, layers...)
+	}
+
+	// In dev mode the working tree wins so edits show up without rebuilding.
+	if *devMode {
+		layers = append(assetfs.Overlay{os.DirFS(".")}, layers...)
+	}
+	return layers, nil
 }
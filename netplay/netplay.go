@@ -0,0 +1,449 @@
+// Package netplay runs a deterministic simulation in lockstep on two
+// machines. The host and the client exchange their inputs for every tick
+// over TCP and only step a tick once both inputs are in, so both sides
+// compute exactly the same game. Local input is scheduled a few ticks ahead
+// to hide the round trip, and the peers compare state checksums now and
+// then to catch a simulation that is not as deterministic as it should be.
+package netplay
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Simulation is the game being played. Step must depend on nothing but the
+// previous state and the inputs, or the peers drift apart.
+type Simulation interface {
+	// Step advances one tick with the input of every player, indexed by
+	// player.
+	Step(inputs []Input)
+	// Checksum summarizes the state, equal on both peers while in sync.
+	Checksum() uint32
+}
+
+// Input is what one player does during one tick.
+type Input struct {
+	// Axis is the paddle direction from -127 to 127.
+	Axis   int8
+	Launch bool
+}
+
+// NewInput quantizes an axis from -1 to 1, so it is sent and replayed
+// exactly.
+func NewInput(axis float32, launch bool) Input {
+	if axis < -1 {
+		axis = -1
+	}
+	if axis > 1 {
+		axis = 1
+	}
+	scaled := axis * 127
+	if scaled < 0 {
+		scaled -= 0.5
+	} else {
+		scaled += 0.5
+	}
+	return Input{Axis: int8(scaled), Launch: launch}
+}
+
+// Value returns the axis from -1 to 1.
+func (in Input) Value() float32 {
+	return float32(in.Axis) / 127
+}
+
+// Players is the number of players in a session, the host and the client.
+const Players = 2
+
+type Config struct {
+	// Delay is how many ticks ahead local input is scheduled. Higher
+	// delays hide longer round trips but make the paddle feel sluggish.
+	Delay int
+	// ChecksumInterval is how often, in ticks, the peers compare state.
+	// Zero turns desync detection off.
+	ChecksumInterval int
+	// Timeout is how long to wait for the peer before giving up on it.
+	Timeout time.Duration
+	// Setup is sent from the host to the client, for anything both need
+	// to agree on before the first tick, like the game mode.
+	Setup string
+}
+
+// DefaultConfig suits a local network.
+var DefaultConfig = Config{
+	Delay:            3,
+	ChecksumInterval: 30,
+	Timeout:          5 * time.Second,
+}
+
+var (
+	// ErrPeerLeft is returned once the peer closed its session.
+	ErrPeerLeft = errors.New("the other player left the game")
+	ErrTimeout  = errors.New("the other player stopped responding")
+)
+
+// DesyncError reports peers whose state differs after the same tick.
+type DesyncError struct {
+	Tick   uint32
+	Local  uint32
+	Remote uint32
+}
+
+func (e *DesyncError) Error() string {
+	return fmt.Sprintf("out of sync at tick %v: checksum %08x, peer has %08x", e.Tick, e.Local, e.Remote)
+}
+
+// Session is one side of a game in lockstep.
+type Session struct {
+	Config
+	// Player is the index of the local player. The host is player 0.
+	Player int
+
+	sim  Simulation
+	conn net.Conn
+	w    *bufio.Writer
+
+	// tick is the next tick to step. Local input has been sent for every
+	// tick before sent.
+	tick   uint32
+	sent   uint32
+	inputs [Players]map[uint32]Input
+
+	// Checksums waiting for the one of the peer for the same tick.
+	sums       map[uint32]uint32
+	remoteSums map[uint32]uint32
+
+	msgs    chan message
+	readErr error
+	// The timeout runs from the first Tick or Idle, not from connecting,
+	// as the game may take a while to get going.
+	started   bool
+	lastHeard time.Time
+	lastSent  time.Time
+	done      chan struct{}
+	closed    bool
+	err       error
+}
+
+// Listener waits for a client to join a hosted game.
+type Listener struct {
+	l net.Listener
+}
+
+// Listen starts hosting on a TCP address such as ":7777".
+func Listen(addr string) (*Listener, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to host: %v", err)
+	}
+	return &Listener{l}, nil
+}
+
+func (l *Listener) Addr() net.Addr {
+	return l.l.Addr()
+}
+
+func (l *Listener) Close() error {
+	return l.l.Close()
+}
+
+// Accept waits for a client and starts a session with it. The client plays
+// with the host's config.
+func (l *Listener) Accept(sim Simulation, cfg Config) (*Session, error) {
+	conn, err := l.l.Accept()
+	if err != nil {
+		return nil, fmt.Errorf("unable to accept player: %v", err)
+	}
+
+	r, w := bufio.NewReader(conn), bufio.NewWriter(conn)
+	conn.SetDeadline(time.Now().Add(cfg.Timeout))
+	err = writeMessage(w, message{kind: msgHello, version: version, player: 1, config: cfg})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		_, err = readHello(r)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("unable to accept player: %v", err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	return newSession(conn, r, w, sim, cfg, 0), nil
+}
+
+// Dial joins the game hosted at addr.
+func Dial(addr string, sim Simulation) (*Session, error) {
+	conn, err := net.DialTimeout("tcp", addr, DefaultConfig.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("unable to join %v: %v", addr, err)
+	}
+
+	r, w := bufio.NewReader(conn), bufio.NewWriter(conn)
+	conn.SetDeadline(time.Now().Add(DefaultConfig.Timeout))
+	hello, err := readHello(r)
+	if err == nil {
+		err = writeMessage(w, message{kind: msgHello, version: version, player: hello.player, config: hello.config})
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil && (hello.player < 1 || hello.player >= Players) {
+		err = fmt.Errorf("host gave out player %v", hello.player)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("unable to join %v: %v", addr, err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	return newSession(conn, r, w, sim, hello.config, hello.player), nil
+}
+
+func readHello(r *bufio.Reader) (message, error) {
+	m, err := readMessage(r)
+	if err != nil {
+		return m, err
+	}
+	if m.kind != msgHello {
+		return m, fmt.Errorf("expected hello, got message type %v", m.kind)
+	}
+	if m.version != version {
+		return m, fmt.Errorf("peer speaks protocol version %v, expected %v", m.version, version)
+	}
+	return m, nil
+}
+
+func newSession(conn net.Conn, r *bufio.Reader, w *bufio.Writer, sim Simulation, cfg Config, player int) *Session {
+	s := &Session{
+		Config:     cfg,
+		Player:     player,
+		sim:        sim,
+		conn:       conn,
+		w:          w,
+		sent:       uint32(cfg.Delay),
+		sums:       make(map[uint32]uint32),
+		remoteSums: make(map[uint32]uint32),
+		msgs:       make(chan message, 256),
+		lastHeard:  time.Now(),
+		lastSent:   time.Now(),
+		done:       make(chan struct{}),
+	}
+	for i := range s.inputs {
+		s.inputs[i] = make(map[uint32]Input)
+	}
+	go s.read(r)
+	return s
+}
+
+// Ticks returns how many ticks have been stepped.
+func (s *Session) Ticks() uint32 {
+	return s.tick
+}
+
+// NeedsInput reports whether the next call to Tick sends its local input.
+// While waiting for the peer, local input is not taken.
+func (s *Session) NeedsInput() bool {
+	return s.sent <= s.tick+uint32(s.Delay)
+}
+
+// Tick sends local input if NeedsInput and steps the simulation one tick if
+// the input of the peer for it is in. It never blocks; false means the
+// tick has to wait. Once it fails every later call fails the same way, and
+// the session should be closed.
+func (s *Session) Tick(local Input) (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	s.start()
+
+	if s.NeedsInput() {
+		s.inputs[s.Player][s.sent] = local
+		s.send(message{kind: msgInput, tick: s.sent, input: local})
+		s.sent++
+	}
+	s.receive()
+
+	stepped := s.err == nil && s.step()
+	// Flushed even after a desync, so the peer gets the checksum and finds
+	// out too.
+	s.flush()
+	if !stepped && s.err == nil && time.Since(s.lastHeard) > s.Timeout {
+		s.fail(ErrTimeout)
+	}
+	return stepped, s.err
+}
+
+// Idle keeps the session alive while the game is not ready to tick yet,
+// like while it loads. The peer waits for it instead of timing out. It
+// never blocks and fails like Tick.
+func (s *Session) Idle() error {
+	if s.err != nil {
+		return s.err
+	}
+	s.start()
+	s.receive()
+	s.flush()
+	if s.err == nil && time.Since(s.lastHeard) > s.Timeout {
+		s.fail(ErrTimeout)
+	}
+	return s.err
+}
+
+func (s *Session) start() {
+	if !s.started {
+		s.started = true
+		s.lastHeard = time.Now()
+	}
+}
+
+// flush sends what was written, or lets the peer know this side is still
+// there when nothing was for a while.
+func (s *Session) flush() {
+	if s.w.Buffered() == 0 && time.Since(s.lastSent) > s.Timeout/4 {
+		s.send(message{kind: msgAlive})
+	}
+	if s.w.Buffered() == 0 {
+		return
+	}
+	if err := s.w.Flush(); err != nil {
+		s.fail(fmt.Errorf("connection lost: %v", err))
+		return
+	}
+	s.lastSent = time.Now()
+}
+
+func (s *Session) step() bool {
+	inputs := make([]Input, Players)
+	// The first ticks are played before anyone could have sent input.
+	if s.tick >= uint32(s.Delay) {
+		for i := range inputs {
+			input, ok := s.inputs[i][s.tick]
+			if !ok {
+				return false
+			}
+			inputs[i] = input
+		}
+	}
+	for i := range s.inputs {
+		delete(s.inputs[i], s.tick)
+	}
+
+	s.sim.Step(inputs)
+	if s.ChecksumInterval > 0 && s.tick%uint32(s.ChecksumInterval) == 0 {
+		sum := s.sim.Checksum()
+		s.sums[s.tick] = sum
+		s.send(message{kind: msgChecksum, tick: s.tick, checksum: sum})
+		s.compare(s.tick)
+	}
+	s.tick++
+	return true
+}
+
+func (s *Session) compare(tick uint32) {
+	local, ok := s.sums[tick]
+	if !ok {
+		return
+	}
+	remote, ok := s.remoteSums[tick]
+	if !ok {
+		return
+	}
+	delete(s.sums, tick)
+	delete(s.remoteSums, tick)
+	if local != remote {
+		s.fail(&DesyncError{tick, local, remote})
+	}
+}
+
+func (s *Session) send(m message) {
+	if s.err != nil {
+		return
+	}
+	if err := writeMessage(s.w, m); err != nil {
+		s.fail(fmt.Errorf("connection lost: %v", err))
+	}
+}
+
+// receive handles every message that came in since the last call.
+func (s *Session) receive() {
+	for s.err == nil {
+		select {
+		case m, ok := <-s.msgs:
+			if !ok {
+				s.fail(fmt.Errorf("connection lost: %v", s.readErr))
+				return
+			}
+			s.lastHeard = time.Now()
+			s.handle(m)
+		default:
+			return
+		}
+	}
+}
+
+func (s *Session) handle(m message) {
+	remote := 1 - s.Player
+	switch m.kind {
+	case msgInput:
+		if m.tick >= s.tick {
+			s.inputs[remote][m.tick] = m.input
+		}
+	case msgChecksum:
+		s.remoteSums[m.tick] = m.checksum
+		s.compare(m.tick)
+	case msgBye:
+		s.fail(ErrPeerLeft)
+	case msgAlive:
+	default:
+		s.fail(fmt.Errorf("unexpected message type %v", m.kind))
+	}
+}
+
+func (s *Session) read(r *bufio.Reader) {
+	defer close(s.msgs)
+	for {
+		m, err := readMessage(r)
+		if err != nil {
+			s.readErr = err
+			return
+		}
+		select {
+		case s.msgs <- m:
+		case <-s.done:
+			return
+		}
+		if m.kind == msgBye {
+			return
+		}
+	}
+}
+
+func (s *Session) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// Err returns the error that ended the session, if any.
+func (s *Session) Err() error {
+	return s.err
+}
+
+// Close tells the peer the game is over and hangs up.
+func (s *Session) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.err == nil {
+		s.send(message{kind: msgBye})
+		s.w.Flush()
+		s.fail(errors.New("session closed"))
+	}
+	close(s.done)
+	return s.conn.Close()
+}
@@ -0,0 +1,224 @@
+package netplay
+
+import (
+	"errors"
+	"hash/crc32"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// counter is a simulation that keeps every input it was stepped with.
+type counter struct {
+	log []Input
+	sum uint32
+	// bad breaks the checksum from this tick on, when set.
+	bad int
+}
+
+func (c *counter) Step(inputs []Input) {
+	for _, in := range inputs {
+		c.log = append(c.log, in)
+		c.sum = crc32.Update(c.sum, crc32.IEEETable, []byte{byte(in.Axis), 0})
+		if in.Launch {
+			c.sum = crc32.Update(c.sum, crc32.IEEETable, []byte{1})
+		}
+	}
+}
+
+func (c *counter) Checksum() uint32 {
+	if c.bad > 0 && len(c.log)/Players >= c.bad {
+		return c.sum + 1
+	}
+	return c.sum
+}
+
+// connect hosts a session on localhost and joins it.
+func connect(t *testing.T, host, client Simulation, cfg Config) (*Session, *Session) {
+	l, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	accepted := make(chan *Session)
+	go func() {
+		s, err := l.Accept(host, cfg)
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- s
+	}()
+
+	c, err := Dial(l.Addr().String(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := <-accepted
+	if h == nil {
+		t.FailNow()
+	}
+	return h, c
+}
+
+// play ticks a session until it stepped ticks times, with input that
+// depends on the player and the tick.
+func play(s *Session, ticks uint32) error {
+	deadline := time.Now().Add(10 * time.Second)
+	for s.Ticks() < ticks {
+		n := int(s.Ticks())
+		in := NewInput(float32(n%7-3)/3*float32(1-2*s.Player), (n+s.Player)%5 == 0)
+		if _, err := s.Tick(in); err != nil {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return errors.New("took too long")
+		}
+		time.Sleep(100 * time.Microsecond)
+	}
+	return nil
+}
+
+func run(hs, cs *Session, ticks uint32) (error, error) {
+	errs := make(chan error)
+	go func() {
+		errs <- play(cs, ticks)
+	}()
+	hostErr := play(hs, ticks)
+	return hostErr, <-errs
+}
+
+func TestLockstep(t *testing.T) {
+	host, client := &counter{}, &counter{}
+	cfg := DefaultConfig
+	cfg.Setup = "versus"
+	hs, cs := connect(t, host, client, cfg)
+	defer hs.Close()
+	defer cs.Close()
+
+	if hs.Player != 0 || cs.Player != 1 {
+		t.Fatalf("players %v and %v", hs.Player, cs.Player)
+	}
+	if cs.Config != cfg {
+		t.Fatalf("client config %+v, host %+v", cs.Config, cfg)
+	}
+
+	if hostErr, clientErr := run(hs, cs, 300); hostErr != nil || clientErr != nil {
+		t.Fatal(hostErr, clientErr)
+	}
+	if !reflect.DeepEqual(host.log, client.log) {
+		t.Fatal("host and client stepped with different inputs")
+	}
+	if len(host.log) != 300*Players {
+		t.Fatalf("stepped %v inputs", len(host.log))
+	}
+
+	// Each player's input shows up delayed, in its own slot.
+	tick := 10
+	want := NewInput(float32((tick-cfg.Delay)%7-3)/3*-1, (tick-cfg.Delay+1)%5 == 0)
+	if got := host.log[tick*Players+1]; got != want {
+		t.Fatalf("client input at tick %v is %+v, want %+v", tick, got, want)
+	}
+}
+
+func TestDesync(t *testing.T) {
+	host, client := &counter{}, &counter{bad: 100}
+	hs, cs := connect(t, host, client, DefaultConfig)
+	defer hs.Close()
+	defer cs.Close()
+
+	hostErr, clientErr := run(hs, cs, 300)
+	for _, err := range []error{hostErr, clientErr} {
+		desync := &DesyncError{}
+		if !errors.As(err, &desync) {
+			t.Fatalf("expected a desync, got %v", err)
+		}
+		if desync.Tick < 100 || desync.Tick > 100+uint32(DefaultConfig.ChecksumInterval) {
+			t.Fatalf("desync found at tick %v", desync.Tick)
+		}
+	}
+}
+
+func TestPeerLeft(t *testing.T) {
+	hs, cs := connect(t, &counter{}, &counter{}, DefaultConfig)
+	defer hs.Close()
+
+	if hostErr, clientErr := run(hs, cs, 50); hostErr != nil || clientErr != nil {
+		t.Fatal(hostErr, clientErr)
+	}
+	cs.Close()
+
+	if err := play(hs, 100); err != ErrPeerLeft {
+		t.Fatalf("expected the client to leave, got %v", err)
+	}
+}
+
+func TestConnectionLost(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Timeout = 200 * time.Millisecond
+	hs, cs := connect(t, &counter{}, &counter{}, cfg)
+	defer hs.Close()
+
+	// Hanging up without a goodbye, like a crash would.
+	cs.conn.Close()
+	if err := play(hs, 100); err == nil || err == ErrPeerLeft {
+		t.Fatalf("expected a lost connection, got %v", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Timeout = 200 * time.Millisecond
+	hs, cs := connect(t, &counter{}, &counter{}, cfg)
+	defer hs.Close()
+	defer cs.Close()
+
+	// The client never ticks.
+	if err := play(hs, 100); err != ErrTimeout {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+}
+
+func TestSlowLoad(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Timeout = 200 * time.Millisecond
+	hs, cs := connect(t, &counter{}, &counter{}, cfg)
+	defer hs.Close()
+	defer cs.Close()
+
+	// Neither side ticks for longer than the timeout after connecting.
+	time.Sleep(2 * cfg.Timeout)
+
+	// Then the client loads for longer still, idling while the host plays.
+	errs := make(chan error)
+	go func() {
+		for start := time.Now(); time.Since(start) < 3*cfg.Timeout; {
+			if err := cs.Idle(); err != nil {
+				errs <- err
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		errs <- play(cs, 100)
+	}()
+	hostErr := play(hs, 100)
+	if clientErr := <-errs; hostErr != nil || clientErr != nil {
+		t.Fatal(hostErr, clientErr)
+	}
+}
+
+func TestInputQuantization(t *testing.T) {
+	for _, axis := range []float32{-2, -1, -0.5, 0, 0.25, 1, 3} {
+		in := NewInput(axis, false)
+		want := axis
+		if want < -1 {
+			want = -1
+		}
+		if want > 1 {
+			want = 1
+		}
+		if d := in.Value() - want; d > 0.005 || d < -0.005 {
+			t.Errorf("axis %v comes back as %v", axis, in.Value())
+		}
+	}
+}
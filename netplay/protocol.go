@@ -0,0 +1,134 @@
+package netplay
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Every message is a type byte followed by a payload whose size depends on
+// the type. Numbers are big endian.
+const (
+	// hello: magic uint32, version uint16, player uint8, delay uint16,
+	// checksum interval uint16, timeout in ms uint32, setup length uint16,
+	// setup.
+	msgHello byte = iota + 1
+	// input: tick uint32, axis int8, buttons uint8.
+	msgInput
+	// checksum: tick uint32, checksum uint32.
+	msgChecksum
+	// bye: no payload.
+	msgBye
+	// alive: no payload. Sent by a peer that has nothing else to send, so
+	// it is not taken for gone while it loads or waits.
+	msgAlive
+)
+
+const (
+	magic = 0x42524b54 // "BRKT"
+	// version is bumped whenever the protocol changes.
+	version = 2
+
+	buttonLaunch = 1 << 0
+)
+
+type message struct {
+	kind byte
+
+	// hello
+	version uint16
+	player  int
+	config  Config
+
+	// input and checksum
+	tick     uint32
+	input    Input
+	checksum uint32
+}
+
+func writeMessage(w *bufio.Writer, m message) error {
+	var buf []byte
+	switch m.kind {
+	case msgHello:
+		buf = make([]byte, 17, 17+len(m.config.Setup))
+		binary.BigEndian.PutUint32(buf[0:], magic)
+		binary.BigEndian.PutUint16(buf[4:], m.version)
+		buf[6] = byte(m.player)
+		binary.BigEndian.PutUint16(buf[7:], uint16(m.config.Delay))
+		binary.BigEndian.PutUint16(buf[9:], uint16(m.config.ChecksumInterval))
+		binary.BigEndian.PutUint32(buf[11:], uint32(m.config.Timeout/time.Millisecond))
+		binary.BigEndian.PutUint16(buf[15:], uint16(len(m.config.Setup)))
+		buf = append(buf, m.config.Setup...)
+	case msgInput:
+		buf = make([]byte, 6)
+		binary.BigEndian.PutUint32(buf[0:], m.tick)
+		buf[4] = byte(m.input.Axis)
+		if m.input.Launch {
+			buf[5] |= buttonLaunch
+		}
+	case msgChecksum:
+		buf = make([]byte, 8)
+		binary.BigEndian.PutUint32(buf[0:], m.tick)
+		binary.BigEndian.PutUint32(buf[4:], m.checksum)
+	case msgBye, msgAlive:
+	default:
+		return fmt.Errorf("unknown message type %v", m.kind)
+	}
+
+	if err := w.WriteByte(m.kind); err != nil {
+		return err
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+func readMessage(r *bufio.Reader) (message, error) {
+	m := message{}
+	kind, err := r.ReadByte()
+	if err != nil {
+		return m, err
+	}
+	m.kind = kind
+
+	switch kind {
+	case msgHello:
+		buf := make([]byte, 17)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return m, err
+		}
+		if binary.BigEndian.Uint32(buf[0:]) != magic {
+			return m, fmt.Errorf("not a breakout peer")
+		}
+		m.version = binary.BigEndian.Uint16(buf[4:])
+		m.player = int(buf[6])
+		m.config.Delay = int(binary.BigEndian.Uint16(buf[7:]))
+		m.config.ChecksumInterval = int(binary.BigEndian.Uint16(buf[9:]))
+		m.config.Timeout = time.Duration(binary.BigEndian.Uint32(buf[11:])) * time.Millisecond
+		setup := make([]byte, binary.BigEndian.Uint16(buf[15:]))
+		if _, err := io.ReadFull(r, setup); err != nil {
+			return m, err
+		}
+		m.config.Setup = string(setup)
+	case msgInput:
+		buf := make([]byte, 6)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return m, err
+		}
+		m.tick = binary.BigEndian.Uint32(buf[0:])
+		m.input.Axis = int8(buf[4])
+		m.input.Launch = buf[5]&buttonLaunch != 0
+	case msgChecksum:
+		buf := make([]byte, 8)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return m, err
+		}
+		m.tick = binary.BigEndian.Uint32(buf[0:])
+		m.checksum = binary.BigEndian.Uint32(buf[4:])
+	case msgBye, msgAlive:
+	default:
+		return m, fmt.Errorf("unknown message type %v", kind)
+	}
+	return m, nil
+}
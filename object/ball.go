@@ -10,6 +10,9 @@ type Ball struct {
 	GameObject
 	Radius float32
 	Stuck  bool
+	// OpenTop lets the ball leave through the top of the screen instead of
+	// bouncing off it, for when that edge is defended by a paddle.
+	OpenTop bool
 }
 
 func (b *Ball) Move(dt float32, windowWidth int) mgl32.Vec2 {
@@ -23,7 +26,7 @@ func (b *Ball) Move(dt float32, windowWidth int) mgl32.Vec2 {
 			b.Position = mgl32.Vec2{float32(windowWidth) - b.Size.X(), b.Position.Y()}
 		}
 
-		if b.Position.Y() <= 0 {
+		if b.Position.Y() <= 0 && !b.OpenTop {
 			b.Velocity = mgl32.Vec2{b.Velocity.X(), -b.Velocity.Y()}
 			b.Position = mgl32.Vec2{b.Position.X(), 0}
 		}
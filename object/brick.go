@@ -0,0 +1,34 @@
+package object
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/le-michael/breakout/texture"
+)
+
+type Brick struct {
+	GameObject
+	// Kind is the tile value the brick was built from.
+	Kind byte
+	// HitPoints is the number of hits left before the brick breaks.
+	HitPoints int
+}
+
+// Hit damages the brick and reports whether it broke. Solid bricks never
+// break.
+func (b *Brick) Hit() bool {
+	if b.IsSolid || b.Destroyed {
+		return false
+	}
+	b.HitPoints--
+	if b.HitPoints <= 0 {
+		b.Destroyed = true
+	}
+	return b.Destroyed
+}
+
+func NewBrick(position, size mgl32.Vec2, color mgl32.Vec3, sprite *texture.Texture2D, kind byte, hitPoints int) *Brick {
+	b := &Brick{Kind: kind, HitPoints: hitPoints}
+	b.GameObject = *NewGameObject(position, size, mgl32.Vec2{}, color, sprite)
+	return b
+}
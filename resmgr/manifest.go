@@ -0,0 +1,217 @@
+package resmgr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/le-michael/breakout/texture"
+)
+
+// Manifest lists every asset the game uses. It is read from a JSON file:
+//
+//	{
+//	  "shaders":  [{"name": "sprite", "vertex": "shaders/sprite.vert", "fragment": "shaders/sprites.frg", "defines": {"TINT": "1"}}],
+//	  "textures": [{"name": "face", "file": "textures/awesomeface.png", "alpha": true, "mipmaps": true}],
+//	  "fonts":    [{"name": "hud", "file": "fonts/hud.ttf"}],
+//	  "sounds":   [{"name": "bounce", "file": "sounds/bounce.wav"}],
+//	  "levels":   [{"name": "one", "file": "levels/one.lvl"}]
+//	}
+type Manifest struct {
+	Shaders  []ShaderAsset  `json:"shaders"`
+	Textures []TextureAsset `json:"textures"`
+	Fonts    []FileAsset    `json:"fonts"`
+	Sounds   []FileAsset    `json:"sounds"`
+	Levels   []FileAsset    `json:"levels"`
+
+	file string
+}
+
+type ShaderAsset struct {
+	Name     string            `json:"name"`
+	Vertex   string            `json:"vertex"`
+	Fragment string            `json:"fragment"`
+	Geometry string            `json:"geometry,omitempty"`
+	Defines  map[string]string `json:"defines"`
+}
+
+type TextureAsset struct {
+	Name string `json:"name"`
+	File string `json:"file"`
+	texture.Options
+}
+
+type FileAsset struct {
+	Name string `json:"name"`
+	File string `json:"file"`
+}
+
+// ManifestError collects every asset of a manifest that failed, so they can
+// all be fixed at once.
+type ManifestError struct {
+	File   string
+	Errors []string
+}
+
+func (m *ManifestError) Error() string {
+	return fmt.Sprintf("%v: %v asset error(s):\n  %v", m.File, len(m.Errors), strings.Join(m.Errors, "\n  "))
+}
+
+func (m *ManifestError) add(format string, args ...interface{}) {
+	m.Errors = append(m.Errors, fmt.Sprintf(format, args...))
+}
+
+func (m *ManifestError) err() error {
+	if len(m.Errors) == 0 {
+		return nil
+	}
+	return m
+}
+
+// ReadManifest parses and validates a manifest without loading anything.
+func ReadManifest(file string) (*Manifest, error) {
+	content, err := fs.ReadFile(rm.FS, file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open manifest: %v", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.DisallowUnknownFields()
+	m := &Manifest{file: file}
+	if err := dec.Decode(m); err != nil {
+		return nil, fmt.Errorf("unable to parse manifest %v: %v", file, err)
+	}
+
+	merr := &ManifestError{File: file}
+	m.validate(merr)
+	if err := merr.err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AssetLoader loads the assets of a manifest one at a time. A loader that
+// works in the background returns nil for what it queued and reports the
+// failures itself once they happen.
+type AssetLoader interface {
+	Shader(asset ShaderAsset) error
+	Texture(asset TextureAsset) error
+	File(asset FileAsset) error
+	Level(asset FileAsset) error
+}
+
+// LoadManifest loads every asset listed in a manifest through l, or right
+// away on the calling thread when l is nil. It keeps going when an asset
+// fails and reports all failures together.
+func LoadManifest(file string, l AssetLoader) (*Manifest, error) {
+	m, err := ReadManifest(file)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil {
+		l = immediate{}
+	}
+
+	merr := &ManifestError{File: file}
+	for _, sh := range m.Shaders {
+		if err := l.Shader(sh); err != nil {
+			merr.add("shader %v: %v", sh.Name, err)
+		}
+	}
+	for _, tex := range m.Textures {
+		if err := l.Texture(tex); err != nil {
+			merr.add("texture %v: %v", tex.Name, err)
+		}
+	}
+	for _, asset := range m.Files() {
+		if err := l.File(asset); err != nil {
+			merr.add("file %v: %v", asset.Name, err)
+		}
+	}
+	for _, lvl := range m.Levels {
+		if err := l.Level(lvl); err != nil {
+			merr.add("level %v: %v", lvl.Name, err)
+		}
+	}
+
+	if err := merr.err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// immediate loads each asset on the calling thread, which must be the GL
+// thread. Levels are only read since building them is up to the level
+// package.
+type immediate struct{}
+
+func (immediate) Shader(sh ShaderAsset) error {
+	return LoadShaderVariant(sh.Vertex, sh.Fragment, sh.Geometry, sh.Defines, sh.Name)
+}
+
+func (immediate) Texture(tex TextureAsset) error {
+	return LoadTextureWithOptions(tex.File, tex.Options, tex.Name)
+}
+
+func (immediate) File(asset FileAsset) error {
+	return LoadFile(asset.File, asset.Name)
+}
+
+func (immediate) Level(asset FileAsset) error {
+	_, err := fs.ReadFile(rm.FS, asset.File)
+	return err
+}
+
+// Files returns the fonts and sounds, which are loaded as raw data.
+func (m *Manifest) Files() []FileAsset {
+	files := append([]FileAsset{}, m.Fonts...)
+	return append(files, m.Sounds...)
+}
+
+func (m *Manifest) validate(merr *ManifestError) {
+	names := make(map[string]bool)
+	unique := func(kind, name string) {
+		if name == "" {
+			merr.add("%v without a name", kind)
+			return
+		}
+		if names[kind+"/"+name] {
+			merr.add("%v %v is listed more than once", kind, name)
+		}
+		names[kind+"/"+name] = true
+	}
+
+	for _, sh := range m.Shaders {
+		unique("shader", sh.Name)
+		if sh.Vertex == "" || sh.Fragment == "" {
+			merr.add("shader %v needs both a vertex and a fragment file", sh.Name)
+		}
+	}
+	for _, tex := range m.Textures {
+		unique("texture", tex.Name)
+		if tex.File == "" {
+			merr.add("texture %v has no file", tex.Name)
+		}
+		if err := tex.Options.Validate(); err != nil {
+			merr.add("texture %v: %v", tex.Name, err)
+		}
+	}
+	files := []struct {
+		kind   string
+		assets []FileAsset
+	}{
+		{"font", m.Fonts},
+		{"sound", m.Sounds},
+		{"level", m.Levels},
+	}
+	for _, group := range files {
+		for _, asset := range group.assets {
+			unique(group.kind, asset.Name)
+			if asset.File == "" {
+				merr.add("%v %v has no file", group.kind, asset.Name)
+			}
+		}
+	}
+}
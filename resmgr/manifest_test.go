@@ -0,0 +1,33 @@
+package resmgr
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadManifest(t *testing.T) {
+	defer SetFS(FS())
+	SetFS(fstest.MapFS{
+		"assets.json": {Data: []byte(`{
+			"textures": [{"name": "face", "file": "textures/face.png", "alpha": true}],
+			"fonts":    [{"name": "hud", "file": "fonts/hud.ttf"}],
+			"sounds":   [{"name": "bounce", "file": "sounds/bounce.wav"}],
+			"levels":   [{"name": "one", "file": "levels/one.lvl"}, {"name": "two", "file": "levels/two.lvl"}]
+		}`)},
+		"fonts/hud.ttf":  {Data: []byte("font")},
+		"levels/one.lvl": {Data: []byte("1 1\n")},
+	})
+
+	_, err := LoadManifest("assets.json", nil)
+	var merr *ManifestError
+	if !errors.As(err, &merr) {
+		t.Fatalf("got %v, want a ManifestError", err)
+	}
+	if len(merr.Errors) != 3 {
+		t.Fatalf("got %v errors, want one each for face, bounce and two:\n%v", len(merr.Errors), err)
+	}
+	if data, err := GetFile("hud"); err != nil || string(data) != "font" {
+		t.Fatalf("font not loaded next to the failures: %q, %v", data, err)
+	}
+}
@@ -1,116 +1,419 @@
 package resmgr
 
 import (
+	"errors"
 	"fmt"
 	"image"
 	"image/draw"
 	_ "image/jpeg"
 	_ "image/png"
-	"io/ioutil"
+	"io/fs"
 	"os"
-
-	"github.com/go-gl/gl/v4.1-core/gl"
+	"path"
+	"sort"
+	"strings"
 
 	"github.com/le-michael/breakout/shader"
 	"github.com/le-michael/breakout/texture"
 )
 
 type resourceManager struct {
-	Textures map[string]*texture.Texture2D
-	Shaders  map[string]*shader.Shader
+	FS fs.FS
+
+	Textures map[string]*textureEntry
+	Shaders  map[string]*shaderEntry
+
+	// Files holds raw assets such as fonts and sounds.
+	Files map[string][]byte
+}
+
+// Every resource starts with one reference held by the manager itself, which
+// Unload drops. Users that hold on to a resource take their own reference
+// with Acquire and give it back with Release. The GPU object is deleted
+// once the count reaches zero.
+type textureEntry struct {
+	tex    *texture.Texture2D
+	refs   int
+	loaded bool
+
+	// file is empty for textures created from an image in memory.
+	file string
+	opts texture.Options
+}
+
+type shaderEntry struct {
+	program *shader.Shader
+	refs    int
+	loaded  bool
+
+	vFile   string
+	fFile   string
+	gFile   string
+	defines map[string]string
+	// files lists every file the program was built from, includes too.
+	files []string
 }
 
 var (
 	rm = &resourceManager{
-		Textures: make(map[string]*texture.Texture2D),
-		Shaders:  make(map[string]*shader.Shader),
+		FS:       os.DirFS("."),
+		Textures: make(map[string]*textureEntry),
+		Shaders:  make(map[string]*shaderEntry),
+		Files:    make(map[string][]byte),
 	}
 )
 
+// SetFS sets the filesystem every resource is loaded from.
+func SetFS(fsys fs.FS) {
+	rm.FS = fsys
+}
+
+func FS() fs.FS {
+	return rm.FS
+}
+
+// LoadShader compiles a shader program under name. If name is already in
+// use the old program is deleted and replaced in place, so existing
+// references use the new program.
 func LoadShader(vFile, fFile, name string) error {
-	program, err := loadShaderFromFile(vFile, fFile)
+	return LoadShaderVariant(vFile, fFile, "", nil, name)
+}
+
+// LoadShaderVariant is LoadShader with an optional geometry stage (gFile may
+// be empty) and extra #define values injected into every stage, for
+// compiling feature variants of the same files.
+func LoadShaderVariant(vFile, fFile, gFile string, defines map[string]string, name string) error {
+	vSource, fSource, gSource, err := ReadShader(vFile, fFile, gFile, defines)
 	if err != nil {
 		return fmt.Errorf("unable to load shader: %v", err)
 	}
-	rm.Shaders[name] = program
+	return UploadShader(vSource, fSource, gSource, defines, name)
+}
+
+// ReadShader reads and preprocesses the sources of a shader program. The
+// geometry source is nil when gFile is empty. It does not touch GL and is
+// safe to call from any goroutine.
+func ReadShader(vFile, fFile, gFile string, defines map[string]string) (vSource, fSource, gSource *shader.Source, err error) {
+	vSource, err = shader.Preprocess(rm.FS, vFile, defines)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	fSource, err = shader.Preprocess(rm.FS, fFile, defines)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if gFile != "" {
+		gSource, err = shader.Preprocess(rm.FS, gFile, defines)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return vSource, fSource, gSource, nil
+}
+
+// UploadShader compiles sources read by ReadShader under name. It must be
+// called from the GL thread.
+func UploadShader(vSource, fSource, gSource *shader.Source, defines map[string]string, name string) error {
+	program, err := shader.CompileSources(vSource, fSource, gSource)
+	if err != nil {
+		return fmt.Errorf("unable to load shader %v: %v", name, err)
+	}
+	setShader(name, program)
+	entry := rm.Shaders[name]
+	entry.vFile = path.Clean(vSource.File)
+	entry.fFile = path.Clean(fSource.File)
+	entry.gFile = ""
+	entry.defines = defines
+	entry.files = append(vSource.Files(), fSource.Files()...)
+	if gSource != nil {
+		entry.gFile = path.Clean(gSource.File)
+		entry.files = append(entry.files, gSource.Files()...)
+	}
 	return nil
 }
 
 func GetShader(name string) (*shader.Shader, error) {
-	program, ok := rm.Shaders[name]
+	entry, ok := rm.Shaders[name]
 	if !ok {
 		return nil, fmt.Errorf("unable to find shader program: %v", name)
 	}
-	return program, nil
+	return entry.program, nil
+}
+
+func AcquireShader(name string) (*shader.Shader, error) {
+	entry, ok := rm.Shaders[name]
+	if !ok {
+		return nil, fmt.Errorf("unable to find shader program: %v", name)
+	}
+	entry.refs++
+	return entry.program, nil
+}
+
+func ReleaseShader(name string) {
+	entry, ok := rm.Shaders[name]
+	if !ok {
+		return
+	}
+	entry.refs--
+	if entry.refs <= 0 {
+		entry.program.Delete()
+		delete(rm.Shaders, name)
+	}
+}
+
+// UnloadShader drops the manager's reference to a shader. The program is
+// deleted right away unless it is still acquired.
+func UnloadShader(name string) {
+	if entry, ok := rm.Shaders[name]; ok && entry.loaded {
+		entry.loaded = false
+		ReleaseShader(name)
+	}
 }
 
 func GetTexture(name string) (*texture.Texture2D, error) {
-	tex, ok := rm.Textures[name]
+	entry, ok := rm.Textures[name]
+	if !ok {
+		return nil, fmt.Errorf("unable to find texture: %v", name)
+	}
+	return entry.tex, nil
+}
+
+func AcquireTexture(name string) (*texture.Texture2D, error) {
+	entry, ok := rm.Textures[name]
 	if !ok {
 		return nil, fmt.Errorf("unable to find texture: %v", name)
 	}
-	return tex, nil
+	entry.refs++
+	return entry.tex, nil
+}
+
+func ReleaseTexture(name string) {
+	entry, ok := rm.Textures[name]
+	if !ok {
+		return
+	}
+	entry.refs--
+	if entry.refs <= 0 {
+		entry.tex.Delete()
+		delete(rm.Textures, name)
+	}
 }
 
+// UnloadTexture drops the manager's reference to a texture. The texture is
+// deleted right away unless it is still acquired.
+func UnloadTexture(name string) {
+	if entry, ok := rm.Textures[name]; ok && entry.loaded {
+		entry.loaded = false
+		ReleaseTexture(name)
+	}
+}
+
+// LoadTexture loads a texture under name. If name is already in use the old
+// texture is deleted and replaced in place, so existing references draw the
+// new image.
 func LoadTexture(tFile string, alpha bool, name string) error {
-	tex, err := loadTextureFromFile(tFile, alpha)
+	return LoadTextureWithOptions(tFile, texture.Options{Alpha: alpha}, name)
+}
+
+func LoadTextureWithOptions(tFile string, opts texture.Options, name string) error {
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("unable to load texture %v: %v", tFile, err)
+	}
+
+	rgba, err := DecodeTexture(tFile)
 	if err != nil {
 		return fmt.Errorf("unable to load texture %v: %v", tFile, err)
 	}
 
-	rm.Textures[name] = tex
+	UploadTexture(tFile, rgba, opts, name)
 	return nil
 }
 
-func Clear() {
-	for _, program := range rm.Shaders {
-		gl.DeleteProgram(program.ID)
+func LoadTextureFromImage(rgba *image.RGBA, alpha bool, name string) {
+	UploadTexture("", rgba, texture.Options{Alpha: alpha}, name)
+}
+
+// UploadTexture creates a texture under name from an image decoded by
+// DecodeTexture. It must be called from the GL thread.
+func UploadTexture(tFile string, rgba *image.RGBA, opts texture.Options, name string) {
+	setTexture(name, newTexture(rgba, opts))
+	entry := rm.Textures[name]
+	entry.file = ""
+	if tFile != "" {
+		entry.file = path.Clean(tFile)
 	}
+	entry.opts = opts
 }
 
-func loadTextureFromFile(tFile string, alpha bool) (*texture.Texture2D, error) {
-	imgFile, err := os.Open(tFile)
+func LoadFile(file, name string) error {
+	data, err := fs.ReadFile(rm.FS, file)
 	if err != nil {
-		return nil, fmt.Errorf("unable to open %v: %v", tFile, err)
+		return fmt.Errorf("unable to open %v: %v", file, err)
 	}
+	AddFile(name, data)
+	return nil
+}
 
-	img, _, err := image.Decode(imgFile)
-	if err != nil {
-		return nil, fmt.Errorf("unable to decode texture: %v", err)
+func AddFile(name string, data []byte) {
+	rm.Files[name] = data
+}
+
+func GetFile(name string) ([]byte, error) {
+	data, ok := rm.Files[name]
+	if !ok {
+		return nil, fmt.Errorf("unable to find file: %v", name)
 	}
+	return data, nil
+}
 
-	rgba := image.NewRGBA(img.Bounds())
-	if rgba.Stride != rgba.Rect.Size().X*4 {
-		return nil, fmt.Errorf("unsupported stride")
+func UnloadFile(name string) {
+	delete(rm.Files, name)
+}
+
+func setTexture(name string, tex *texture.Texture2D) {
+	entry, ok := rm.Textures[name]
+	if !ok {
+		rm.Textures[name] = &textureEntry{tex: tex, refs: 1, loaded: true}
+		return
 	}
-	draw.Draw(rgba, rgba.Bounds(), img, image.Point{0, 0}, draw.Src)
 
-	tex := texture.New()
-	tex.Generate(rgba)
+	entry.tex.Delete()
+	*entry.tex = *tex
+	if !entry.loaded {
+		entry.loaded = true
+		entry.refs++
+	}
+}
 
-	if alpha {
-		tex.ImageFormat = gl.RGBA
-		tex.InternalFormat = gl.RGBA
+func setShader(name string, program *shader.Shader) {
+	entry, ok := rm.Shaders[name]
+	if !ok {
+		rm.Shaders[name] = &shaderEntry{program: program, refs: 1, loaded: true}
+		return
 	}
 
-	return tex, nil
+	entry.program.Delete()
+	*entry.program = *program
+	if !entry.loaded {
+		entry.loaded = true
+		entry.refs++
+	}
 }
 
-func loadShaderFromFile(vFile, fFile string) (*shader.Shader, error) {
-	vSource, err := ioutil.ReadFile(vFile)
-	if err != nil {
-		return nil, fmt.Errorf("unable to open %v: %v", vFile, err)
+// Reload reloads every shader and texture that was loaded from file. The
+// resources are updated in place so existing references see the new
+// version. If a resource fails to reload, the last good version is kept.
+func Reload(file string) error {
+	file = path.Clean(file)
+	errs := []string{}
+
+	for name, entry := range rm.Shaders {
+		if !contains(entry.files, file) {
+			continue
+		}
+		vSource, fSource, gSource, err := ReadShader(entry.vFile, entry.fFile, entry.gFile, entry.defines)
+		if err == nil {
+			err = UploadShader(vSource, fSource, gSource, entry.defines, name)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("unable to reload shader %v: %v", name, err))
+		}
 	}
 
-	fSource, err := ioutil.ReadFile(fFile)
+	for name, entry := range rm.Textures {
+		if entry.file != file {
+			continue
+		}
+		rgba, err := DecodeTexture(entry.file)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("unable to reload texture %v: %v", name, err))
+			continue
+		}
+		entry.tex.Generate(rgba)
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// Leaks lists the resources that are still acquired by someone.
+func Leaks() []string {
+	leaks := []string{}
+	for name, entry := range rm.Shaders {
+		if refs := acquired(entry.refs, entry.loaded); refs > 0 {
+			leaks = append(leaks, fmt.Sprintf("shader %v: %v reference(s)", name, refs))
+		}
+	}
+	for name, entry := range rm.Textures {
+		if refs := acquired(entry.refs, entry.loaded); refs > 0 {
+			leaks = append(leaks, fmt.Sprintf("texture %v: %v reference(s)", name, refs))
+		}
+	}
+	sort.Strings(leaks)
+	return leaks
+}
+
+func acquired(refs int, loaded bool) int {
+	if loaded {
+		return refs - 1
+	}
+	return refs
+}
+
+// Clear deletes every resource regardless of outstanding references.
+func Clear() {
+	for name, entry := range rm.Shaders {
+		entry.program.Delete()
+		delete(rm.Shaders, name)
+	}
+	for name, entry := range rm.Textures {
+		entry.tex.Delete()
+		delete(rm.Textures, name)
+	}
+	for name := range rm.Files {
+		delete(rm.Files, name)
+	}
+}
+
+// DecodeTexture reads and decodes an image into RGBA. It does not touch GL
+// and is safe to call from any goroutine.
+func DecodeTexture(tFile string) (*image.RGBA, error) {
+	imgFile, err := rm.FS.Open(tFile)
 	if err != nil {
-		return nil, fmt.Errorf("unable to open %v: %v", vFile, err)
+		return nil, fmt.Errorf("unable to open %v: %v", tFile, err)
 	}
+	defer imgFile.Close()
 
-	program, err := shader.Compile(string(vSource), string(fSource))
+	img, _, err := image.Decode(imgFile)
 	if err != nil {
-		return nil, fmt.Errorf("unable to compile shader program: %v", err)
+		return nil, fmt.Errorf("unable to decode texture: %v", err)
 	}
 
-	return program, nil
+	rgba := image.NewRGBA(img.Bounds())
+	if rgba.Stride != rgba.Rect.Size().X*4 {
+		return nil, fmt.Errorf("unsupported stride")
+	}
+	draw.Draw(rgba, rgba.Bounds(), img, image.Point{0, 0}, draw.Src)
+
+	return rgba, nil
+}
+
+func newTexture(rgba *image.RGBA, opts texture.Options) *texture.Texture2D {
+	tex := texture.NewWithOptions(opts)
+	tex.Generate(rgba)
+	return tex
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,101 @@
+package score
+
+// Rules decide how many points everything is worth.
+type Rules struct {
+	// BrickPoints are awarded for breaking a brick, by brick kind.
+	BrickPoints map[byte]int
+	// HitPoints are awarded for a hit that damages a brick without breaking
+	// it.
+	HitPoints int
+
+	// Every ComboStep consecutive brick hits without touching the paddle
+	// raise the multiplier by one, up to MaxMultiplier.
+	ComboStep     int
+	MaxMultiplier int
+
+	// Clearing a level awards LifeBonus per remaining life and TimeBonus per
+	// second left under ParTime.
+	LifeBonus int
+	TimeBonus int
+	ParTime   float32
+}
+
+var DefaultRules = Rules{
+	BrickPoints: map[byte]int{
+		2: 10,
+		3: 20,
+		4: 30,
+		5: 50,
+	},
+	HitPoints: 5,
+
+	ComboStep:     4,
+	MaxMultiplier: 8,
+
+	LifeBonus: 500,
+	TimeBonus: 10,
+	ParTime:   120,
+}
+
+// Score keeps the points of a single game.
+type Score struct {
+	Rules  Rules
+	Points int
+
+	// Combo counts the brick hits since the ball last touched the paddle.
+	Combo     int
+	BestCombo int
+}
+
+func (s *Score) Multiplier() int {
+	if s.Rules.ComboStep <= 0 {
+		return 1
+	}
+	m := 1 + s.Combo/s.Rules.ComboStep
+	if s.Rules.MaxMultiplier > 0 && m > s.Rules.MaxMultiplier {
+		m = s.Rules.MaxMultiplier
+	}
+	return m
+}
+
+// Brick scores a hit on a breakable brick of the given kind and returns the
+// points awarded.
+func (s *Score) Brick(kind byte, broke bool) int {
+	s.Combo++
+	if s.Combo > s.BestCombo {
+		s.BestCombo = s.Combo
+	}
+
+	points := s.Rules.HitPoints
+	if broke {
+		points = s.Rules.BrickPoints[kind]
+	}
+	points *= s.Multiplier()
+	s.Points += points
+	return points
+}
+
+// Paddle ends the current combo.
+func (s *Score) Paddle() {
+	s.Combo = 0
+}
+
+// BallLost ends the current combo.
+func (s *Score) BallLost() {
+	s.Combo = 0
+}
+
+// LevelCleared awards the bonus for clearing a level with lives left after
+// elapsed seconds and returns it.
+func (s *Score) LevelCleared(lives int, elapsed float32) int {
+	bonus := lives * s.Rules.LifeBonus
+	if left := s.Rules.ParTime - elapsed; left > 0 {
+		bonus += int(left) * s.Rules.TimeBonus
+	}
+	s.Points += bonus
+	return bonus
+}
+
+func New(rules Rules) *Score {
+	return &Score{Rules: rules}
+}
@@ -0,0 +1,73 @@
+package score
+
+import "testing"
+
+func TestCombo(t *testing.T) {
+	s := New(DefaultRules)
+
+	// The hit completing the first ComboStep already scores at x2.
+	var got []int
+	for i := 0; i < 6; i++ {
+		got = append(got, s.Brick(2, true))
+	}
+	want := []int{10, 10, 10, 20, 20, 20}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("hits scored %v, want %v", got, want)
+		}
+	}
+	if s.Points != 90 || s.Combo != 6 || s.BestCombo != 6 {
+		t.Fatalf("points %v, combo %v, best %v", s.Points, s.Combo, s.BestCombo)
+	}
+
+	// A damaging hit keeps the combo going at the current multiplier.
+	if p := s.Brick(4, false); p != 2*DefaultRules.HitPoints {
+		t.Fatalf("damaging hit scored %v", p)
+	}
+
+	s.Paddle()
+	if s.Combo != 0 || s.Multiplier() != 1 || s.BestCombo != 7 {
+		t.Fatalf("combo %v, multiplier %v, best %v after the paddle", s.Combo, s.Multiplier(), s.BestCombo)
+	}
+	s.Brick(3, true)
+	s.BallLost()
+	if s.Combo != 0 {
+		t.Fatalf("combo %v after losing the ball", s.Combo)
+	}
+}
+
+func TestMultiplier(t *testing.T) {
+	tests := []struct {
+		rules Rules
+		combo int
+		want  int
+	}{
+		{DefaultRules, 0, 1},
+		{DefaultRules, 3, 1},
+		{DefaultRules, 4, 2},
+		{DefaultRules, 11, 3},
+		{DefaultRules, 1000, DefaultRules.MaxMultiplier},
+		{Rules{ComboStep: 2}, 1000, 501},
+		{Rules{}, 1000, 1},
+	}
+	for _, test := range tests {
+		s := &Score{Rules: test.rules, Combo: test.combo}
+		if got := s.Multiplier(); got != test.want {
+			t.Errorf("combo %v with %+v: multiplier %v, want %v", test.combo, test.rules, got, test.want)
+		}
+	}
+}
+
+func TestLevelCleared(t *testing.T) {
+	s := New(DefaultRules)
+	if bonus := s.LevelCleared(2, 100.5); bonus != 2*500+19*10 {
+		t.Fatalf("bonus %v", bonus)
+	}
+	// No time bonus once past par.
+	if bonus := s.LevelCleared(1, 200); bonus != 500 {
+		t.Fatalf("bonus %v past par", bonus)
+	}
+	if s.Points != 1690 {
+		t.Fatalf("points %v", s.Points)
+	}
+}
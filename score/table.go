@@ -0,0 +1,123 @@
+package score
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// TableSize is the number of entries a high-score table keeps.
+const TableSize = 10
+
+type Entry struct {
+	Name   string    `json:"name"`
+	Points int       `json:"points"`
+	Level  int       `json:"level"`
+	Date   time.Time `json:"date"`
+}
+
+// Table is a high-score table stored as a JSON file. Entries are sorted
+// best first.
+type Table struct {
+	Entries []Entry
+
+	file string
+}
+
+// Qualifies reports whether points would make it into the table.
+func (t *Table) Qualifies(points int) bool {
+	if points <= 0 {
+		return false
+	}
+	return len(t.Entries) < TableSize || points > t.Entries[len(t.Entries)-1].Points
+}
+
+// Add inserts an entry and returns its rank starting at 0, or -1 if it did
+// not make it into the table. Ties keep the older entry first.
+func (t *Table) Add(e Entry) int {
+	if !t.Qualifies(e.Points) {
+		return -1
+	}
+
+	rank := sort.Search(len(t.Entries), func(i int) bool {
+		return t.Entries[i].Points < e.Points
+	})
+	t.Entries = append(t.Entries, Entry{})
+	copy(t.Entries[rank+1:], t.Entries[rank:])
+	t.Entries[rank] = e
+	if len(t.Entries) > TableSize {
+		t.Entries = t.Entries[:TableSize]
+	}
+	return rank
+}
+
+// Save writes the table to its file. The file is replaced in one step so a
+// crash never leaves a truncated table behind.
+func (t *Table) Save() error {
+	if t.file == "" {
+		return fmt.Errorf("unable to save high scores: table has no file")
+	}
+
+	content, err := json.MarshalIndent(t.Entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to save high scores: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(t.file), 0755); err != nil {
+		return fmt.Errorf("unable to save high scores: %v", err)
+	}
+	tmp := t.file + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return fmt.Errorf("unable to save high scores: %v", err)
+	}
+	if err := os.Rename(tmp, t.file); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("unable to save high scores: %v", err)
+	}
+	return nil
+}
+
+// File returns where the high-score table lives, in the user config
+// directory.
+func File() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to find config directory: %v", err)
+	}
+	return filepath.Join(dir, "breakout", "highscores.json"), nil
+}
+
+// Load reads a high-score table. A missing file is an empty table.
+func Load(file string) (*Table, error) {
+	t := NewTable(file)
+
+	content, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read high scores: %v", err)
+	}
+
+	if err := json.Unmarshal(content, &t.Entries); err != nil {
+		return nil, fmt.Errorf("unable to parse high scores %v: %v", file, err)
+	}
+	sort.SliceStable(t.Entries, func(i, j int) bool {
+		return t.Entries[i].Points > t.Entries[j].Points
+	})
+	if len(t.Entries) > TableSize {
+		t.Entries = t.Entries[:TableSize]
+	}
+	return t, nil
+}
+
+// NewTable returns an empty table saved to file. With an empty file name
+// the table is kept in memory only.
+func NewTable(file string) *Table {
+	return &Table{file: file}
+}
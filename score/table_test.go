@@ -0,0 +1,110 @@
+package score
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTableOrder(t *testing.T) {
+	table := NewTable("")
+	for i, points := range []int{300, 100, 500, 300, 200} {
+		table.Add(Entry{Name: string(rune('A' + i)), Points: points})
+	}
+
+	var names string
+	for _, e := range table.Entries {
+		names += e.Name
+	}
+	// Ties keep the older entry first.
+	if names != "CADEB" {
+		t.Fatalf("entries ordered %v", names)
+	}
+
+	if rank := table.Add(Entry{Name: "F", Points: 400}); rank != 1 {
+		t.Fatalf("400 ranked %v", rank)
+	}
+}
+
+func TestQualifies(t *testing.T) {
+	table := NewTable("")
+	if table.Qualifies(0) || table.Qualifies(-5) {
+		t.Fatal("a score without points qualifies")
+	}
+	for i := 0; i < TableSize; i++ {
+		if rank := table.Add(Entry{Points: 100 * (i + 1)}); rank != 0 {
+			t.Fatalf("entry %v ranked %v", i, rank)
+		}
+	}
+
+	// A full table only takes scores beating its last entry.
+	if table.Qualifies(100) {
+		t.Fatal("a score tying the last entry qualifies")
+	}
+	if table.Add(Entry{Points: 50}) != -1 || len(table.Entries) != TableSize {
+		t.Fatalf("low score added, %v entries", len(table.Entries))
+	}
+	if !table.Qualifies(101) {
+		t.Fatal("a score beating the last entry does not qualify")
+	}
+	if rank := table.Add(Entry{Points: 150}); rank != TableSize-1 {
+		t.Fatalf("150 ranked %v", rank)
+	}
+	if len(table.Entries) != TableSize || table.Entries[TableSize-1].Points != 150 {
+		t.Fatalf("table not trimmed: %+v", table.Entries)
+	}
+}
+
+func TestTableRoundTrip(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "breakout", "highscores.json")
+
+	table, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Entries) != 0 {
+		t.Fatalf("missing file loaded %+v", table.Entries)
+	}
+
+	date := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	table.Add(Entry{Name: "AAA", Points: 1200, Level: 2, Date: date})
+	table.Add(Entry{Name: "BBB", Points: 3400, Level: 3, Date: date})
+	if err := table.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(file + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("temporary file left behind: %v", err)
+	}
+
+	loaded, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Entries, table.Entries) {
+		t.Fatalf("loaded %+v, saved %+v", loaded.Entries, table.Entries)
+	}
+
+	// Files edited by hand are sorted again.
+	if err := os.WriteFile(file, []byte(`[{"name": "LOW", "points": 1}, {"name": "HIGH", "points": 9}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err = Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Entries[0].Name != "HIGH" {
+		t.Fatalf("loaded %+v", loaded.Entries)
+	}
+
+	if err := os.WriteFile(file, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(file); err == nil {
+		t.Fatal("loaded a broken file")
+	}
+	if err := NewTable("").Save(); err == nil {
+		t.Fatal("saved a table without a file")
+	}
+}
@@ -0,0 +1,201 @@
+package shader
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Block describes an active uniform block of a linked program.
+type Block struct {
+	Index uint32
+	// Size is the size of the block data in bytes as reported by GL.
+	Size int32
+	// Members are the block's uniforms by name.
+	Members map[string]Member
+}
+
+type Member struct {
+	Offset int32
+	Type   uint32
+}
+
+// FrameBlock is the name of the per-frame block shared by every program,
+// declared in shaders/frame.glsl. Programs that use it get it bound to
+// FrameBinding when they are compiled.
+const (
+	FrameBlock   = "Frame"
+	FrameBinding = 0
+)
+
+// FrameData mirrors the Frame block with std140 layout, including the
+// trailing padding that rounds the block up to a multiple of 16 bytes.
+type FrameData struct {
+	Projection mgl32.Mat4 `glsl:"projection"`
+	View       mgl32.Mat4 `glsl:"view"`
+	ScreenSize mgl32.Vec2 `glsl:"screenSize"`
+	Time       float32    `glsl:"time"`
+	_          float32
+}
+
+// UniformBuffer is a buffer bound to a uniform block binding point.
+type UniformBuffer struct {
+	ID      uint32
+	Binding uint32
+	Size    int
+}
+
+// Update uploads data, a pointer to a struct laid out like the block, and
+// binds the buffer to its binding point.
+func (u *UniformBuffer) Update(data interface{}) {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Ptr || int(v.Elem().Type().Size()) != u.Size {
+		panic(fmt.Sprintf("uniform buffer of %v bytes updated with %T", u.Size, data))
+	}
+
+	gl.BindBuffer(gl.UNIFORM_BUFFER, u.ID)
+	gl.BufferSubData(gl.UNIFORM_BUFFER, 0, u.Size, unsafe.Pointer(v.Pointer()))
+	gl.BindBuffer(gl.UNIFORM_BUFFER, 0)
+	gl.BindBufferBase(gl.UNIFORM_BUFFER, u.Binding, u.ID)
+}
+
+func (u *UniformBuffer) Delete() {
+	gl.DeleteBuffers(1, &u.ID)
+	u.ID = 0
+}
+
+// NewUniformBuffer allocates a buffer sized for data, a pointer to a struct
+// laid out like the block, uploads it and binds it to the binding point.
+func NewUniformBuffer(binding uint32, data interface{}) *UniformBuffer {
+	u := &UniformBuffer{
+		Binding: binding,
+		Size:    int(reflect.TypeOf(data).Elem().Size()),
+	}
+	gl.GenBuffers(1, &u.ID)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, u.ID)
+	gl.BufferData(gl.UNIFORM_BUFFER, u.Size, nil, gl.DYNAMIC_DRAW)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, 0)
+	u.Update(data)
+	return u
+}
+
+// BindBlock connects a uniform block of the program to a binding point.
+func (s *Shader) BindBlock(name string, binding uint32) error {
+	block, ok := s.Blocks[name]
+	if !ok {
+		return fmt.Errorf("shader %v has no uniform block %v", s.ID, name)
+	}
+	gl.UniformBlockBinding(s.ID, block.Index, binding)
+	return nil
+}
+
+var goTypes = map[reflect.Type]uint32{
+	reflect.TypeOf(float32(0)):   gl.FLOAT,
+	reflect.TypeOf(int32(0)):     gl.INT,
+	reflect.TypeOf(mgl32.Vec2{}): gl.FLOAT_VEC2,
+	reflect.TypeOf(mgl32.Vec3{}): gl.FLOAT_VEC3,
+	reflect.TypeOf(mgl32.Vec4{}): gl.FLOAT_VEC4,
+	reflect.TypeOf(mgl32.Mat4{}): gl.FLOAT_MAT4,
+}
+
+// ValidateBlock checks that the fields of v, a struct whose fields are
+// tagged with the GLSL member names, line up with the layout GL reports for
+// the block. Every member of the block must be covered by a field of the
+// same type at the same offset, and the struct must be large enough to fill
+// the whole block.
+func (s *Shader) ValidateBlock(name string, v interface{}) error {
+	block, ok := s.Blocks[name]
+	if !ok {
+		return fmt.Errorf("shader %v has no uniform block %v", s.ID, name)
+	}
+
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("uniform block %v: %v is not a struct", name, t)
+	}
+
+	problems := []string{}
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		if tag := t.Field(i).Tag.Get("glsl"); tag != "" {
+			fields[tag] = t.Field(i)
+		}
+	}
+
+	for member, m := range block.Members {
+		field, ok := fields[member]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%v has no field for %v", t, member))
+			continue
+		}
+		if int32(field.Offset) != m.Offset {
+			problems = append(problems, fmt.Sprintf("%v.%v is at offset %v, GL expects %v", t, field.Name, field.Offset, m.Offset))
+		}
+		if goTypes[field.Type] != m.Type {
+			problems = append(problems, fmt.Sprintf("%v.%v is %v, GL expects %v", t, field.Name, field.Type, typeName(m.Type)))
+		}
+	}
+	if int32(t.Size()) < block.Size {
+		problems = append(problems, fmt.Sprintf("%v is %v bytes, GL expects %v", t, t.Size(), block.Size))
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("uniform block %v does not match %v:\n  %v", name, t, strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// reflectBlocks records the active uniform blocks of the linked program.
+func (s *Shader) reflectBlocks() {
+	s.Blocks = make(map[string]Block)
+
+	var count, maxLength int32
+	gl.GetProgramiv(s.ID, gl.ACTIVE_UNIFORM_BLOCKS, &count)
+	gl.GetProgramiv(s.ID, gl.ACTIVE_UNIFORM_BLOCK_MAX_NAME_LENGTH, &maxLength)
+	buf := make([]uint8, maxLength+1)
+	for i := uint32(0); i < uint32(count); i++ {
+		var length int32
+		gl.GetActiveUniformBlockName(s.ID, i, maxLength+1, &length, &buf[0])
+		name := string(buf[:length])
+
+		block := Block{Index: i, Members: make(map[string]Member)}
+		gl.GetActiveUniformBlockiv(s.ID, i, gl.UNIFORM_BLOCK_DATA_SIZE, &block.Size)
+
+		var memberCount int32
+		gl.GetActiveUniformBlockiv(s.ID, i, gl.UNIFORM_BLOCK_ACTIVE_UNIFORMS, &memberCount)
+		if memberCount > 0 {
+			indices := make([]int32, memberCount)
+			gl.GetActiveUniformBlockiv(s.ID, i, gl.UNIFORM_BLOCK_ACTIVE_UNIFORM_INDICES, &indices[0])
+			uindices := make([]uint32, memberCount)
+			for j, index := range indices {
+				uindices[j] = uint32(index)
+			}
+
+			offsets := make([]int32, memberCount)
+			types := make([]int32, memberCount)
+			gl.GetActiveUniformsiv(s.ID, memberCount, &uindices[0], gl.UNIFORM_OFFSET, &offsets[0])
+			gl.GetActiveUniformsiv(s.ID, memberCount, &uindices[0], gl.UNIFORM_TYPE, &types[0])
+
+			var nameLength int32
+			gl.GetProgramiv(s.ID, gl.ACTIVE_UNIFORM_MAX_LENGTH, &nameLength)
+			nameBuf := make([]uint8, nameLength+1)
+			for j, index := range uindices {
+				gl.GetActiveUniformName(s.ID, index, nameLength+1, &length, &nameBuf[0])
+				member := strings.TrimSuffix(string(nameBuf[:length]), "[0]")
+				member = strings.TrimPrefix(member, name+".")
+				block.Members[member] = Member{offsets[j], uint32(types[j])}
+			}
+		}
+
+		s.Blocks[name] = block
+	}
+}
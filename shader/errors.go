@@ -0,0 +1,118 @@
+package shader
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type Stage string
+
+const (
+	VertexStage   Stage = "vertex"
+	FragmentStage Stage = "fragment"
+	GeometryStage Stage = "geometry"
+	LinkStage     Stage = "link"
+)
+
+// Diagnostic is a single message from the driver. Line is 0 when the driver
+// did not point at a line, which is usual for link errors; Message is then
+// the raw log line.
+type Diagnostic struct {
+	File     string
+	Line     int
+	Severity string
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	if d.Line == 0 {
+		return d.Message
+	}
+	return fmt.Sprintf("%v:%v: %v: %v", d.File, d.Line, d.Severity, d.Message)
+}
+
+// CompileError reports a failed stage along with the parsed driver log.
+type CompileError struct {
+	Stage       Stage
+	File        string
+	Diagnostics []Diagnostic
+	// Log is the unparsed driver output.
+	Log string
+}
+
+func (e *CompileError) Error() string {
+	msgs := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		msgs[i] = d.String()
+	}
+
+	what := fmt.Sprintf("%v shader %v failed to compile", e.Stage, e.File)
+	if e.Stage == LinkStage {
+		what = fmt.Sprintf("program %v failed to link", e.File)
+	}
+	return fmt.Sprintf("%v:\n  %v", what, strings.Join(msgs, "\n  "))
+}
+
+func newCompileError(stage Stage, src *Source, log string) *CompileError {
+	e := &CompileError{
+		Stage: stage,
+		Log:   log,
+	}
+	if src != nil {
+		e.File = src.File
+	}
+
+	for _, line := range strings.Split(log, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		e.Diagnostics = append(e.Diagnostics, parseDiagnostic(line, src))
+	}
+	return e
+}
+
+// Line references as printed by the common drivers:
+//
+//	Mesa:   0:12(3): error: ...
+//	Apple:  ERROR: 0:12: ...
+//	NVIDIA: 0(12) : error C0000: ...
+var logLine = regexp.MustCompile(`(?m)^((?:ERROR|WARNING): )?\d+(?::(\d+)(?:\(\d+\))?|\((\d+)\))`)
+
+func parseDiagnostic(line string, src *Source) Diagnostic {
+	d := Diagnostic{Severity: "error", Message: line}
+	if strings.Contains(strings.ToLower(line), "warning") {
+		d.Severity = "warning"
+	}
+
+	m := logLine.FindStringSubmatchIndex(line)
+	if m == nil || src == nil {
+		return d
+	}
+	var number string
+	if m[4] >= 0 {
+		number = line[m[4]:m[5]]
+	} else {
+		number = line[m[6]:m[7]]
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return d
+	}
+
+	origin := src.Origin(n)
+	d.File = origin.File
+	d.Line = origin.Line
+
+	// Drop the location and the severity the drivers repeat in the message.
+	msg := strings.TrimLeft(line[m[1]:], " :")
+	for _, prefix := range []string{"error", "warning"} {
+		if strings.HasPrefix(strings.ToLower(msg), prefix) {
+			msg = strings.TrimLeft(msg[len(prefix):], " :")
+		}
+	}
+	d.Message = msg
+	return d
+}
@@ -0,0 +1,150 @@
+package shader
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Source is preprocessed shader code along with the origin of every line,
+// so compiler messages can point at the file that was actually edited.
+type Source struct {
+	File string
+	Code string
+	// Lines[i] is where line i+1 of Code came from.
+	Lines []Origin
+}
+
+type Origin struct {
+	File string
+	Line int
+}
+
+func (o Origin) String() string {
+	return fmt.Sprintf("%v:%v", o.File, o.Line)
+}
+
+var includeDirective = regexp.MustCompile(`^\s*#\s*include\s+"([^"]+)"\s*$`)
+
+// Preprocess reads a shader and resolves its #include "file" directives,
+// relative to the including file. Each file is included at most once. The
+// defines are injected as #define lines right after #version.
+func Preprocess(fsys fs.FS, file string, defines map[string]string) (*Source, error) {
+	p := &preprocessor{
+		fsys:     fsys,
+		included: make(map[string]bool),
+		src:      &Source{File: file},
+	}
+	if err := p.include(path.Clean(file), nil); err != nil {
+		return nil, err
+	}
+	p.injectDefines(defines)
+
+	p.src.Code = strings.Join(p.lines, "\n") + "\n"
+	return p.src, nil
+}
+
+// Plain wraps code that was not read from a file.
+func Plain(name, code string) *Source {
+	lines := strings.Split(strings.TrimSuffix(code, "\n"), "\n")
+	src := &Source{File: name, Code: code, Lines: make([]Origin, len(lines))}
+	for i := range lines {
+		src.Lines[i] = Origin{name, i + 1}
+	}
+	return src
+}
+
+type preprocessor struct {
+	fsys     fs.FS
+	included map[string]bool
+	lines    []string
+	src      *Source
+}
+
+func (p *preprocessor) include(file string, stack []string) error {
+	for _, parent := range stack {
+		if parent == file {
+			return fmt.Errorf("include cycle: %v -> %v", strings.Join(stack, " -> "), file)
+		}
+	}
+	if p.included[file] {
+		return nil
+	}
+	p.included[file] = true
+
+	content, err := fs.ReadFile(p.fsys, file)
+	if err != nil {
+		if len(stack) > 0 {
+			return fmt.Errorf("%v: unable to include %v: %v", stack[len(stack)-1], file, err)
+		}
+		return fmt.Errorf("unable to open %v: %v", file, err)
+	}
+
+	stack = append(stack, file)
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	for i, line := range lines {
+		if m := includeDirective.FindStringSubmatch(line); m != nil {
+			if err := p.include(path.Join(path.Dir(file), m[1]), stack); err != nil {
+				return err
+			}
+			continue
+		}
+		p.lines = append(p.lines, strings.TrimSuffix(line, "\r"))
+		p.src.Lines = append(p.src.Lines, Origin{file, i + 1})
+	}
+	return nil
+}
+
+func (p *preprocessor) injectDefines(defines map[string]string) {
+	if len(defines) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(defines))
+	for name := range defines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	injected := make([]string, len(names))
+	origins := make([]Origin, len(names))
+	for i, name := range names {
+		injected[i] = strings.TrimSpace("#define " + name + " " + defines[name])
+		origins[i] = Origin{"<defines>", i + 1}
+	}
+
+	// #version has to stay the first statement.
+	at := 0
+	for i, line := range p.lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "#version") {
+			at = i + 1
+			break
+		}
+	}
+	p.lines = append(p.lines[:at], append(injected, p.lines[at:]...)...)
+	p.src.Lines = append(p.src.Lines[:at], append(origins, p.src.Lines[at:]...)...)
+}
+
+// Files returns every file that contributed to the source.
+func (s *Source) Files() []string {
+	seen := make(map[string]bool)
+	files := []string{}
+	for _, origin := range s.Lines {
+		if !seen[origin.File] && origin.File != "<defines>" {
+			seen[origin.File] = true
+			files = append(files, origin.File)
+		}
+	}
+	return files
+}
+
+// Origin maps a line number of Code back to where it came from.
+func (s *Source) Origin(line int) Origin {
+	if line < 1 || line > len(s.Lines) {
+		return Origin{s.File, line}
+	}
+	return s.Lines[line-1]
+}
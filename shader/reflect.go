@@ -0,0 +1,122 @@
+package shader
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// Variable describes an active uniform or attribute of a linked program.
+type Variable struct {
+	Location int32
+	Type     uint32
+	// Size is the number of elements for arrays, 1 otherwise.
+	Size int32
+}
+
+var (
+	floatTypes = []uint32{gl.FLOAT, gl.BOOL}
+	intTypes   = []uint32{
+		gl.INT, gl.BOOL,
+		gl.SAMPLER_2D, gl.SAMPLER_2D_ARRAY, gl.SAMPLER_2D_MULTISAMPLE, gl.SAMPLER_CUBE,
+	}
+	vec2Types = []uint32{gl.FLOAT_VEC2}
+	vec3Types = []uint32{gl.FLOAT_VEC3}
+	vec4Types = []uint32{gl.FLOAT_VEC4}
+	mat4Types = []uint32{gl.FLOAT_MAT4}
+)
+
+var typeNames = map[uint32]string{
+	gl.FLOAT:                  "float",
+	gl.FLOAT_VEC2:             "vec2",
+	gl.FLOAT_VEC3:             "vec3",
+	gl.FLOAT_VEC4:             "vec4",
+	gl.FLOAT_MAT3:             "mat3",
+	gl.FLOAT_MAT4:             "mat4",
+	gl.INT:                    "int",
+	gl.BOOL:                   "bool",
+	gl.SAMPLER_2D:             "sampler2D",
+	gl.SAMPLER_2D_ARRAY:       "sampler2DArray",
+	gl.SAMPLER_2D_MULTISAMPLE: "sampler2DMS",
+	gl.SAMPLER_CUBE:           "samplerCube",
+}
+
+func typeName(t uint32) string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%x", t)
+}
+
+// reflect records the active uniforms, attributes and uniform blocks of the
+// linked program.
+func (s *Shader) reflect() {
+	s.reflectBlocks()
+
+	s.Uniforms = make(map[string]Variable)
+	s.Attributes = make(map[string]Variable)
+	s.warned = make(map[string]bool)
+
+	var count, maxLength int32
+	gl.GetProgramiv(s.ID, gl.ACTIVE_UNIFORMS, &count)
+	gl.GetProgramiv(s.ID, gl.ACTIVE_UNIFORM_MAX_LENGTH, &maxLength)
+	buf := make([]uint8, maxLength+1)
+	for i := int32(0); i < count; i++ {
+		var length, size int32
+		var xtype uint32
+		gl.GetActiveUniform(s.ID, uint32(i), maxLength+1, &length, &size, &xtype, &buf[0])
+		name := string(buf[:length])
+
+		// Members of uniform blocks have no location of their own.
+		location := gl.GetUniformLocation(s.ID, gl.Str(name+"\x00"))
+		if location < 0 {
+			continue
+		}
+		s.Uniforms[strings.TrimSuffix(name, "[0]")] = Variable{location, xtype, size}
+	}
+
+	gl.GetProgramiv(s.ID, gl.ACTIVE_ATTRIBUTES, &count)
+	gl.GetProgramiv(s.ID, gl.ACTIVE_ATTRIBUTE_MAX_LENGTH, &maxLength)
+	buf = make([]uint8, maxLength+1)
+	for i := int32(0); i < count; i++ {
+		var length, size int32
+		var xtype uint32
+		gl.GetActiveAttrib(s.ID, uint32(i), maxLength+1, &length, &size, &xtype, &buf[0])
+		name := string(buf[:length])
+
+		location := gl.GetAttribLocation(s.ID, gl.Str(name+"\x00"))
+		s.Attributes[name] = Variable{location, xtype, size}
+	}
+}
+
+// location looks up a cached uniform location and checks that the uniform
+// has one of the accepted types. Problems are logged once per uniform and -1
+// is returned, which GL silently ignores.
+func (s *Shader) location(name string, accepted []uint32) int32 {
+	uniform, ok := s.Uniforms[name]
+	if !ok {
+		s.warnOnce(name, "shader %v: setting unknown or inactive uniform %q", s.ID, name)
+		return -1
+	}
+
+	for _, t := range accepted {
+		if uniform.Type == t {
+			return uniform.Location
+		}
+	}
+	s.warnOnce(name, "shader %v: uniform %q is %v, not %v", s.ID, name, typeName(uniform.Type), typeName(accepted[0]))
+	return -1
+}
+
+func (s *Shader) warnOnce(name, format string, args ...interface{}) {
+	if s.warned[name] {
+		return
+	}
+	if s.warned == nil {
+		s.warned = make(map[string]bool)
+	}
+	s.warned[name] = true
+	log.Printf(format, args...)
+}
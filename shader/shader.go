@@ -1,7 +1,6 @@
 package shader
 
 import (
-	"fmt"
 	"strings"
 
 	"github.com/go-gl/gl/v4.1-core/gl"
@@ -10,94 +9,140 @@ import (
 
 type Shader struct {
 	ID uint32
+
+	// Active uniforms and attributes by name, read back after linking.
+	Uniforms   map[string]Variable
+	Attributes map[string]Variable
+	Blocks     map[string]Block
+
+	warned map[string]bool
 }
 
 func (s *Shader) Use() {
 	gl.UseProgram(s.ID)
 }
 
+func (s *Shader) Delete() {
+	gl.DeleteProgram(s.ID)
+	s.ID = 0
+}
+
 func (s *Shader) SetFloat(name string, value float32, useShader bool) {
 	if useShader {
 		s.Use()
 	}
-	gl.Uniform1f(s.GetUniformLocation(name), value)
+	gl.Uniform1f(s.location(name, floatTypes), value)
 }
 
 func (s *Shader) SetInteger(name string, value int32, useShader bool) {
 	if useShader {
 		s.Use()
 	}
-	gl.Uniform1i(s.GetUniformLocation(name), value)
+	gl.Uniform1i(s.location(name, intTypes), value)
 }
 
 func (s *Shader) SetVector2f(name string, x, y float32, useShader bool) {
 	if useShader {
 		s.Use()
 	}
-	gl.Uniform2f(s.GetUniformLocation(name), x, y)
+	gl.Uniform2f(s.location(name, vec2Types), x, y)
 }
 
 func (s *Shader) SetVector2fv(name string, vec mgl32.Vec2, useShader bool) {
 	if useShader {
 		s.Use()
 	}
-	gl.Uniform2fv(s.GetUniformLocation(name), 1, &vec[0])
+	gl.Uniform2fv(s.location(name, vec2Types), 1, &vec[0])
 }
 
 func (s *Shader) SetVector3f(name string, x, y, z float32, useShader bool) {
 	if useShader {
 		s.Use()
 	}
-	gl.Uniform3f(s.GetUniformLocation(name), x, y, z)
+	gl.Uniform3f(s.location(name, vec3Types), x, y, z)
 }
 
 func (s *Shader) SetVector3fv(name string, vec mgl32.Vec3, useShader bool) {
 	if useShader {
 		s.Use()
 	}
-	gl.Uniform3fv(s.GetUniformLocation(name), 1, &vec[0])
+	gl.Uniform3fv(s.location(name, vec3Types), 1, &vec[0])
 }
 
 func (s *Shader) SetVector4f(name string, x, y, z, w float32, useShader bool) {
 	if useShader {
 		s.Use()
 	}
-	gl.Uniform4f(s.GetUniformLocation(name), x, y, z, w)
+	gl.Uniform4f(s.location(name, vec4Types), x, y, z, w)
 }
 
 func (s *Shader) SetVector4fv(name string, vec mgl32.Vec4, useShader bool) {
 	if useShader {
 		s.Use()
 	}
-	gl.Uniform4fv(s.GetUniformLocation(name), 1, &vec[0])
+	gl.Uniform4fv(s.location(name, vec4Types), 1, &vec[0])
 }
 
 func (s *Shader) SetMatrix4(name string, mat mgl32.Mat4, useShader bool) {
 	if useShader {
 		s.Use()
 	}
-	gl.UniformMatrix4fv(s.GetUniformLocation(name), 1, false, &mat[0])
+	gl.UniformMatrix4fv(s.location(name, mat4Types), 1, false, &mat[0])
 }
 
+// GetUniformLocation returns the cached location of an active uniform, or
+// -1 if the program has no such uniform.
 func (s *Shader) GetUniformLocation(name string) int32 {
-	return gl.GetUniformLocation(s.ID, gl.Str(name+"\x00"))
+	if uniform, ok := s.Uniforms[name]; ok {
+		return uniform.Location
+	}
+	return -1
 }
 
 func Compile(vertexSource, fragmentSource string) (*Shader, error) {
-	sVertex, err := compile(vertexSource, gl.VERTEX_SHADER)
-	if err != nil {
-		return nil, err
+	return CompileSources(Plain("vertex", vertexSource), Plain("fragment", fragmentSource), nil)
+}
+
+// CompileSources compiles preprocessed sources, reporting compiler errors
+// against the original files and lines. The geometry stage is optional and
+// skipped when nil. Every GL object is deleted again if a stage fails.
+func CompileSources(vertexSource, fragmentSource, geometrySource *Source) (*Shader, error) {
+	stages := []struct {
+		stage      Stage
+		shaderType uint32
+		source     *Source
+	}{
+		{VertexStage, gl.VERTEX_SHADER, vertexSource},
+		{FragmentStage, gl.FRAGMENT_SHADER, fragmentSource},
+		{GeometryStage, gl.GEOMETRY_SHADER, geometrySource},
 	}
 
-	sFragment, err := compile(fragmentSource, gl.FRAGMENT_SHADER)
-	if err != nil {
-		return nil, err
+	shaders := []uint32{}
+	defer func() {
+		for _, shader := range shaders {
+			gl.DeleteShader(shader)
+		}
+	}()
+
+	for _, st := range stages {
+		if st.source == nil {
+			continue
+		}
+		shader, err := compile(st.source, st.shaderType, st.stage)
+		if err != nil {
+			return nil, err
+		}
+		shaders = append(shaders, shader)
 	}
 
 	program := gl.CreateProgram()
-	gl.AttachShader(program, sVertex)
-	gl.AttachShader(program, sFragment)
+	for _, shader := range shaders {
+		gl.AttachShader(program, shader)
+	}
 	gl.LinkProgram(program)
+	for _, shader := range shaders {
+		gl.DetachShader(program, shader)
+	}
 
 	var status int32
 	gl.GetProgramiv(program, gl.LINK_STATUS, &status)
@@ -107,21 +152,33 @@ func Compile(vertexSource, fragmentSource string) (*Shader, error) {
 
 		log := strings.Repeat("\x00", int(logLength+1))
 		gl.GetProgramInfoLog(program, logLength, nil, gl.Str(log))
+		gl.DeleteProgram(program)
 
-		return nil, fmt.Errorf("failed to link program: %v", log)
+		err := newCompileError(LinkStage, nil, strings.TrimRight(log, "\x00"))
+		err.File = vertexSource.File + " + " + fragmentSource.File
+		if geometrySource != nil {
+			err.File += " + " + geometrySource.File
+		}
+		return nil, err
 	}
 
-	gl.DeleteShader(sVertex)
-	gl.DeleteShader(sFragment)
-
 	s := &Shader{ID: program}
+	s.reflect()
+
+	if _, ok := s.Blocks[FrameBlock]; ok {
+		if err := s.ValidateBlock(FrameBlock, FrameData{}); err != nil {
+			s.Delete()
+			return nil, err
+		}
+		s.BindBlock(FrameBlock, FrameBinding)
+	}
 	return s, nil
 }
 
-func compile(source string, shaderType uint32) (uint32, error) {
+func compile(source *Source, shaderType uint32, stage Stage) (uint32, error) {
 	shader := gl.CreateShader(shaderType)
 
-	csouce, free := gl.Strs(source)
+	csouce, free := gl.Strs(source.Code + "\x00")
 	gl.ShaderSource(shader, 1, csouce, nil)
 	free()
 	gl.CompileShader(shader)
@@ -134,8 +191,9 @@ func compile(source string, shaderType uint32) (uint32, error) {
 
 		log := strings.Repeat("\x00", int(logLength+1))
 		gl.GetShaderInfoLog(shader, logLength, nil, gl.Str(log))
+		gl.DeleteShader(shader)
 
-		return 0, fmt.Errorf("failed to compile %v: %v", source, log)
+		return 0, newCompileError(stage, source, strings.TrimRight(log, "\x00"))
 	}
 
 	return shader, nil
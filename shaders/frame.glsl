@@ -0,0 +1,7 @@
+// Per-frame data shared by every program, filled from shader.FrameData.
+layout (std140) uniform Frame {
+    mat4 projection;
+    mat4 view;
+    vec2 screenSize;
+    float time;
+};
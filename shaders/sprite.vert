@@ -3,11 +3,11 @@ layout (location = 0) in vec4 vertex; // <vec2 position, vec2 texCoords>
 
 out vec2 TexCoords;
 
+#include "frame.glsl"
+
 uniform mat4 model;
-uniform mat4 projection;
 
 void main() {
     TexCoords = vertex.zw;
-    gl_Position = projection * model * vec4(vertex.xy, 0.0, 1.0);
+    gl_Position = projection * view * model * vec4(vertex.xy, 0.0, 1.0);
 }
-
@@ -54,6 +54,12 @@ func (s *SpriteRenderer) Draw(tex *texture.Texture2D, position mgl32.Vec2, size
 	s.Shader.SetMatrix4("model", model, false)
 	s.Shader.SetVector3fv("spriteColor", color, false)
 
+	if tex.Premultiplied {
+		gl.BlendFunc(gl.ONE, gl.ONE_MINUS_SRC_ALPHA)
+	} else {
+		gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	}
+
 	gl.ActiveTexture(gl.TEXTURE0)
 	tex.Bind()
 
@@ -0,0 +1,102 @@
+package text
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/le-michael/breakout/sprite"
+	"github.com/le-michael/breakout/texture"
+)
+
+// TextRenderer draws text with a built-in 5x7 pixel font. Every run of lit
+// pixels is drawn as a quad of a plain white texture, so no font assets are
+// required.
+type TextRenderer struct {
+	Renderer *sprite.SpriteRenderer
+	White    *texture.Texture2D
+}
+
+func (t *TextRenderer) Draw(s string, position mgl32.Vec2, scale float32, color mgl32.Vec3) {
+	s = strings.ReplaceAll(s, "\t", "    ")
+	for i, line := range strings.Split(s, "\n") {
+		y := position.Y() + float32(i*cellHeight)*scale
+		for j, r := range []rune(line) {
+			x := position.X() + float32(j*cellWidth)*scale
+			t.drawGlyph(glyph(r), mgl32.Vec2{x, y}, scale, color)
+		}
+	}
+}
+
+func (t *TextRenderer) drawGlyph(rows [glyphHeight]uint8, position mgl32.Vec2, scale float32, color mgl32.Vec3) {
+	for y, row := range rows {
+		for x := 0; x < glyphWidth; {
+			if row&(1<<(glyphWidth-1-x)) == 0 {
+				x++
+				continue
+			}
+			start := x
+			for x < glyphWidth && row&(1<<(glyphWidth-1-x)) != 0 {
+				x++
+			}
+			pos := position.Add(mgl32.Vec2{float32(start), float32(y)}.Mul(scale))
+			size := mgl32.Vec2{float32(x - start), 1}.Mul(scale)
+			t.Renderer.Draw(t.White, pos, size, 0, color)
+		}
+	}
+}
+
+// Size returns the width and height of s when drawn at scale.
+func Size(s string, scale float32) mgl32.Vec2 {
+	lines := strings.Split(s, "\n")
+	longest := 0
+	for _, line := range lines {
+		if n := len([]rune(line)); n > longest {
+			longest = n
+		}
+	}
+	return mgl32.Vec2{
+		float32(longest*cellWidth) * scale,
+		float32(len(lines)*cellHeight) * scale,
+	}
+}
+
+// Wrap breaks s into lines of at most width characters, splitting on spaces
+// where possible. It returns s unchanged when width is less than one.
+func Wrap(s string, width int) string {
+	if width < 1 {
+		return s
+	}
+	var out []string
+	for _, line := range strings.Split(s, "\n") {
+		runes := []rune(line)
+		for len(runes) > width {
+			cut := width
+			for i := width; i > 0; i-- {
+				if runes[i] == ' ' {
+					cut = i
+					break
+				}
+			}
+			out = append(out, string(runes[:cut]))
+			runes = []rune(strings.TrimLeft(string(runes[cut:]), " "))
+		}
+		out = append(out, string(runes))
+	}
+	return strings.Join(out, "\n")
+}
+
+func glyph(r rune) [glyphHeight]uint8 {
+	if g, ok := glyphs[unicode.ToUpper(r)]; ok {
+		return g
+	}
+	return glyphs['?']
+}
+
+func New(renderer *sprite.SpriteRenderer, white *texture.Texture2D) *TextRenderer {
+	return &TextRenderer{
+		Renderer: renderer,
+		White:    white,
+	}
+}
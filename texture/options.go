@@ -0,0 +1,101 @@
+package texture
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+type Wrap string
+
+const (
+	WrapRepeat Wrap = "repeat"
+	WrapClamp  Wrap = "clamp"
+	WrapMirror Wrap = "mirror"
+)
+
+type Filter string
+
+const (
+	// FilterLinear is bilinear, or trilinear when combined with mipmaps.
+	FilterLinear Filter = "linear"
+	// FilterNearest keeps hard pixel edges for pixel art.
+	FilterNearest Filter = "nearest"
+)
+
+// Options control how a texture is stored and sampled. The zero value is an
+// opaque, linearly filtered, repeating texture.
+type Options struct {
+	Alpha bool `json:"alpha"`
+	// SRGB stores the texture in an sRGB format so it is linearized when
+	// sampled. Only useful when rendering to an sRGB framebuffer.
+	SRGB        bool   `json:"srgb"`
+	Premultiply bool   `json:"premultiply"`
+	Mipmaps     bool   `json:"mipmaps"`
+	Filter      Filter `json:"filter"`
+	Wrap        Wrap   `json:"wrap"`
+
+	// InternalFormat overrides the format chosen from Alpha and SRGB.
+	InternalFormat int32 `json:"-"`
+}
+
+func (o Options) Validate() error {
+	switch o.Wrap {
+	case "", WrapRepeat, WrapClamp, WrapMirror:
+	default:
+		return fmt.Errorf("unknown wrap mode %q", o.Wrap)
+	}
+	switch o.Filter {
+	case "", FilterLinear, FilterNearest:
+	default:
+		return fmt.Errorf("unknown filter %q", o.Filter)
+	}
+	if o.Premultiply && !o.Alpha {
+		return fmt.Errorf("premultiply requires alpha")
+	}
+	return nil
+}
+
+func (o Options) apply(t *Texture2D) {
+	switch {
+	case o.InternalFormat != 0:
+		t.InternalFormat = o.InternalFormat
+	case o.Alpha && o.SRGB:
+		t.InternalFormat = gl.SRGB8_ALPHA8
+	case o.Alpha:
+		t.InternalFormat = gl.RGBA8
+	case o.SRGB:
+		t.InternalFormat = gl.SRGB8
+	default:
+		t.InternalFormat = gl.RGB8
+	}
+	t.ImageFormat = gl.RGBA
+	t.Premultiplied = o.Premultiply
+
+	switch o.Wrap {
+	case WrapClamp:
+		t.WrapS, t.WrapT = gl.CLAMP_TO_EDGE, gl.CLAMP_TO_EDGE
+	case WrapMirror:
+		t.WrapS, t.WrapT = gl.MIRRORED_REPEAT, gl.MIRRORED_REPEAT
+	default:
+		t.WrapS, t.WrapT = gl.REPEAT, gl.REPEAT
+	}
+
+	nearest := o.Filter == FilterNearest
+	t.Mipmaps = o.Mipmaps
+	switch {
+	case o.Mipmaps && nearest:
+		t.FilterMin = gl.NEAREST_MIPMAP_NEAREST
+	case o.Mipmaps:
+		t.FilterMin = gl.LINEAR_MIPMAP_LINEAR
+	case nearest:
+		t.FilterMin = gl.NEAREST
+	default:
+		t.FilterMin = gl.LINEAR
+	}
+	if nearest {
+		t.FilterMax = gl.NEAREST
+	} else {
+		t.FilterMax = gl.LINEAR
+	}
+}
@@ -16,23 +16,33 @@ type Texture2D struct {
 	WrapT          int32
 	FilterMin      int32
 	FilterMax      int32
+	Mipmaps        bool
+
+	// Premultiplied textures have their color multiplied by alpha on upload
+	// and must be blended with (ONE, ONE_MINUS_SRC_ALPHA).
+	Premultiplied bool
 }
 
 func (t *Texture2D) Generate(rgba *image.RGBA) {
 	t.Width = uint32(rgba.Rect.Size().X)
 	t.Height = uint32(rgba.Rect.Size().Y)
 
+	pix := rgba.Pix
+	if t.Premultiplied {
+		pix = premultiply(pix)
+	}
+
 	gl.BindTexture(gl.TEXTURE_2D, t.ID)
 	gl.TexImage2D(
 		gl.TEXTURE_2D,
 		0,
-		gl.RGBA,
+		t.InternalFormat,
 		int32(t.Width),
 		int32(t.Height),
 		0,
-		gl.RGBA,
+		uint32(t.ImageFormat),
 		gl.UNSIGNED_BYTE,
-		gl.Ptr(rgba.Pix),
+		gl.Ptr(pix),
 	)
 
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, int32(t.WrapS))
@@ -40,6 +50,27 @@ func (t *Texture2D) Generate(rgba *image.RGBA) {
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, int32(t.FilterMin))
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, int32(t.FilterMax))
 
+	if t.Mipmaps {
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+	}
+
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+}
+
+// Allocate reserves storage for a width x height texture without uploading
+// any pixels, for use as a render target.
+func (t *Texture2D) Allocate(width, height int) {
+	t.Width = uint32(width)
+	t.Height = uint32(height)
+
+	gl.BindTexture(gl.TEXTURE_2D, t.ID)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, t.InternalFormat, int32(width), int32(height), 0, uint32(t.ImageFormat), gl.UNSIGNED_BYTE, nil)
+
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, int32(t.WrapS))
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, int32(t.WrapT))
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, int32(t.FilterMin))
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, int32(t.FilterMax))
+
 	gl.BindTexture(gl.TEXTURE_2D, 0)
 }
 
@@ -47,6 +78,25 @@ func (t *Texture2D) Bind() {
 	gl.BindTexture(gl.TEXTURE_2D, t.ID)
 }
 
+func (t *Texture2D) Delete() {
+	gl.DeleteTextures(1, &t.ID)
+	t.ID = 0
+}
+
+func premultiply(pix []uint8) []uint8 {
+	out := make([]uint8, len(pix))
+	for i := 0; i+3 < len(pix); i += 4 {
+		a := uint32(pix[i+3])
+		out[i] = uint8((uint32(pix[i])*a + 127) / 255)
+		out[i+1] = uint8((uint32(pix[i+1])*a + 127) / 255)
+		out[i+2] = uint8((uint32(pix[i+2])*a + 127) / 255)
+		out[i+3] = pix[i+3]
+	}
+	return out
+}
+
+// New creates a texture with default settings. Generate always receives
+// RGBA pixels, the internal format decides whether alpha is kept.
 func New() *Texture2D {
 	var id uint32
 	gl.GenTextures(1, &id)
@@ -55,10 +105,18 @@ func New() *Texture2D {
 		Width:          0,
 		Height:         0,
 		InternalFormat: gl.RGB,
-		ImageFormat:    gl.RGB,
+		ImageFormat:    gl.RGBA,
 		WrapS:          gl.REPEAT,
 		WrapT:          gl.REPEAT,
 		FilterMin:      gl.LINEAR,
 		FilterMax:      gl.LINEAR,
 	}
 }
+
+// NewWithOptions creates a texture configured by opts. Options must be set
+// before Generate since they affect how the image is uploaded.
+func NewWithOptions(opts Options) *Texture2D {
+	t := New()
+	opts.apply(t)
+	return t
+}
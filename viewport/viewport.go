@@ -0,0 +1,91 @@
+package viewport
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Viewport maps a fixed virtual resolution onto the framebuffer, keeping the
+// aspect ratio by adding black bars on the sides (pillarbox) or on the top
+// and bottom (letterbox).
+type Viewport struct {
+	VirtualWidth  int
+	VirtualHeight int
+
+	X      int32
+	Y      int32
+	Width  int32
+	Height int32
+
+	FramebufferWidth  int
+	FramebufferHeight int
+}
+
+func (v *Viewport) Resize(fbWidth, fbHeight int) {
+	v.FramebufferWidth = fbWidth
+	v.FramebufferHeight = fbHeight
+	if fbWidth <= 0 || fbHeight <= 0 {
+		v.X, v.Y, v.Width, v.Height = 0, 0, 0, 0
+		return
+	}
+
+	scale := float32(fbWidth) / float32(v.VirtualWidth)
+	if sy := float32(fbHeight) / float32(v.VirtualHeight); sy < scale {
+		scale = sy
+	}
+
+	v.Width = int32(float32(v.VirtualWidth) * scale)
+	v.Height = int32(float32(v.VirtualHeight) * scale)
+	v.X = (int32(fbWidth) - v.Width) / 2
+	v.Y = (int32(fbHeight) - v.Height) / 2
+}
+
+func (v *Viewport) Apply() {
+	gl.Viewport(v.X, v.Y, v.Width, v.Height)
+}
+
+// Clear clears the whole framebuffer to black, then the playfield area to
+// the given color.
+func (v *Viewport) Clear(color mgl32.Vec4) {
+	gl.Viewport(0, 0, int32(v.FramebufferWidth), int32(v.FramebufferHeight))
+	gl.ClearColor(0, 0, 0, 1)
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	gl.Enable(gl.SCISSOR_TEST)
+	gl.Scissor(v.X, v.Y, v.Width, v.Height)
+	gl.ClearColor(color.X(), color.Y(), color.Z(), color.W())
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+	gl.Disable(gl.SCISSOR_TEST)
+
+	v.Apply()
+}
+
+func (v *Viewport) Projection() mgl32.Mat4 {
+	return mgl32.Ortho(0, float32(v.VirtualWidth), float32(v.VirtualHeight), 0, -1, 1)
+}
+
+// ToVirtual converts a position in window coordinates into virtual
+// coordinates. Window and framebuffer sizes differ on HiDPI displays, so the
+// window size is required to scale the position first.
+func (v *Viewport) ToVirtual(x, y float64, windowWidth, windowHeight int) mgl32.Vec2 {
+	if windowWidth <= 0 || windowHeight <= 0 || v.Width == 0 || v.Height == 0 {
+		return mgl32.Vec2{}
+	}
+	fx := float32(x) * float32(v.FramebufferWidth) / float32(windowWidth)
+	fy := float32(y) * float32(v.FramebufferHeight) / float32(windowHeight)
+
+	// Y is measured from the bottom in GL but the bars are symmetric, so it
+	// is also the offset from the top.
+	vx := (fx - float32(v.X)) * float32(v.VirtualWidth) / float32(v.Width)
+	vy := (fy - float32(v.Y)) * float32(v.VirtualHeight) / float32(v.Height)
+	return mgl32.Vec2{vx, vy}
+}
+
+func New(virtualWidth, virtualHeight int) *Viewport {
+	v := &Viewport{
+		VirtualWidth:  virtualWidth,
+		VirtualHeight: virtualHeight,
+	}
+	v.Resize(virtualWidth, virtualHeight)
+	return v
+}
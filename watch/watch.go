@@ -0,0 +1,91 @@
+package watch
+
+import (
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+)
+
+type stamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watcher polls directories for added or modified files. Polling is slower
+// than OS notifications but behaves the same on every platform and
+// filesystem.
+type Watcher struct {
+	FS       fs.FS
+	Dirs     []string
+	Interval time.Duration
+
+	files    map[string]stamp
+	lastPoll time.Time
+}
+
+// Poll returns the files that were added or modified since the previous
+// poll. It returns nothing if called again before Interval has elapsed.
+func (w *Watcher) Poll() ([]string, error) {
+	now := time.Now()
+	if now.Sub(w.lastPoll) < w.Interval {
+		return nil, nil
+	}
+	w.lastPoll = now
+
+	files, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	changed := []string{}
+	for file, st := range files {
+		if old, ok := w.files[file]; !ok || old != st {
+			changed = append(changed, file)
+		}
+	}
+	sort.Strings(changed)
+
+	w.files = files
+	return changed, nil
+}
+
+func (w *Watcher) scan() (map[string]stamp, error) {
+	files := make(map[string]stamp)
+	for _, dir := range w.Dirs {
+		err := fs.WalkDir(w.FS, dir, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			files[path.Clean(file)] = stamp{info.ModTime(), info.Size()}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func New(fsys fs.FS, interval time.Duration, dirs ...string) (*Watcher, error) {
+	w := &Watcher{
+		FS:       fsys,
+		Dirs:     dirs,
+		Interval: interval,
+		lastPoll: time.Now(),
+	}
+
+	files, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+	w.files = files
+	return w, nil
+}